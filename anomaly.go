@@ -0,0 +1,125 @@
+package databox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ErrAnomaly is returned when AnomalyGuard blocks a push.
+var ErrAnomaly = errors.New("anomalous value")
+
+// AnomalyRule describes the expected values of a metric.
+type AnomalyRule struct {
+	// Range bounds the values, if set.
+	Range *ValueRange
+	// MaxChange limits the relative change against the previous value
+	// successfully pushed by this process, e.g. 0.5 allows values from 50 %
+	// to 150 % of the previous value. Anomalous values are never the
+	// previous value. Zero disables the check.
+	MaxChange float64
+}
+
+// Anomaly describes a value violating its AnomalyRule.
+type Anomaly struct {
+	Key   string
+	Value float64
+	// Previous is the previous value, if HasPrevious is set.
+	Previous    float64
+	HasPrevious bool
+	// Reason describes the violation.
+	Reason string
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("metric %q value %v %s", a.Key, a.Value, a.Reason)
+}
+
+// AnomalyGuard catches obviously wrong values, e.g. daily revenue 1000x the
+// norm, before they reach the dashboards. AnomalyGuard is safe for concurrent
+// use.
+type AnomalyGuard struct {
+	// Rules by metric key. Metrics without rule aren't checked.
+	Rules map[string]AnomalyRule
+	// Block rejects pushes with anomalous values by ErrAnomaly. Otherwise the
+	// values are pushed and only reported.
+	Block bool
+	// OnAnomaly, if set, is called for every anomalous value.
+	OnAnomaly func(Anomaly)
+
+	mu   sync.Mutex
+	last map[string]float64
+}
+
+// check checks the KPIs. The returned commit function remembers their values
+// for the next check, except the anomalous ones; it's meant to be called
+// after a successful push.
+func (g *AnomalyGuard) check(kpis []KPI) (func(), error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var anomalies []Anomaly
+	values := make(map[string]float64)
+	for _, kpi := range kpis {
+		if kpi.Key != "" {
+			values[kpi.Key] = exactFloat64(kpi.Value)
+			anomalies = g.checkMetric(anomalies, kpi.Key, exactFloat64(kpi.Value))
+		}
+		for key, value := range kpi.Metrics {
+			values[key] = exactFloat64(value)
+			anomalies = g.checkMetric(anomalies, key, exactFloat64(value))
+		}
+	}
+
+	for _, anomaly := range anomalies {
+		if g.OnAnomaly != nil {
+			if err := g.reportAnomaly(anomaly); err != nil {
+				return func() {}, err
+			}
+		}
+	}
+	if g.Block && len(anomalies) > 0 {
+		return func() {}, fmt.Errorf("%w: %v", ErrAnomaly, anomalies[0])
+	}
+
+	// Anomalous values don't become the baseline of the next check.
+	for _, anomaly := range anomalies {
+		delete(values, anomaly.Key)
+	}
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.last == nil {
+			g.last = make(map[string]float64)
+		}
+		for key, value := range values {
+			g.last[key] = value
+		}
+	}, nil
+}
+
+func (g *AnomalyGuard) checkMetric(anomalies []Anomaly, key string, value float64) []Anomaly {
+	rule, ok := g.Rules[key]
+	if !ok {
+		return anomalies
+	}
+	previous, hasPrevious := g.last[key]
+	anomaly := Anomaly{Key: key, Value: value, Previous: previous, HasPrevious: hasPrevious}
+
+	if r := rule.Range; r != nil && (value < r.Min || value > r.Max) {
+		anomaly.Reason = fmt.Sprintf("is out of range [%v, %v]", r.Min, r.Max)
+		return append(anomalies, anomaly)
+	}
+	if rule.MaxChange > 0 && hasPrevious {
+		change := math.Abs(value-previous) / math.Abs(previous)
+		if previous == 0 && value == 0 {
+			change = 0
+		}
+		if change > rule.MaxChange {
+			anomaly.Reason = fmt.Sprintf("changed from %v by more than %v %%", previous, rule.MaxChange*100)
+			return append(anomalies, anomaly)
+		}
+	}
+	return anomalies
+}
@@ -0,0 +1,75 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAnomalyGuard(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	var anomalies []Anomaly
+	client.AnomalyGuard = &AnomalyGuard{
+		Rules: map[string]AnomalyRule{
+			"revenue": {Range: &ValueRange{Min: 0, Max: 1e6}, MaxChange: 2},
+		},
+		Block:     true,
+		OnAnomaly: func(a Anomaly) { anomalies = append(anomalies, a) },
+	}
+
+	push := func(value float32) error {
+		_, err := client.InsertAll(context.Background(), []KPI{{Key: "revenue", Value: value}, {Key: "other", Value: 1e9}}, false)
+		return err
+	}
+	if err := push(1000); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if err := push(2500); err != nil {
+		t.Error("Change within limit must pass", err)
+	}
+	if err := push(250000); !errors.Is(err, ErrAnomaly) {
+		t.Error("Expected ErrAnomaly", err)
+	}
+	if err := push(-1); !errors.Is(err, ErrAnomaly) {
+		t.Error("Expected ErrAnomaly", err)
+	}
+	if calls != 2 || len(anomalies) != 2 || anomalies[0].Previous != 2500 || !anomalies[0].HasPrevious {
+		t.Error("Unexpected anomalies", calls, anomalies)
+	}
+
+	client.AnomalyGuard.Block = false
+	if err := push(250000); err != nil {
+		t.Error("Anomaly must be only reported", err)
+	}
+	if calls != 3 || len(anomalies) != 3 {
+		t.Error("Unexpected anomalies", calls, anomalies)
+	}
+
+	if err := push(-0.1); err != nil || anomalies[3].Value != -0.1 {
+		t.Error("Anomaly value must keep its decimal representation", anomalies[3])
+	}
+	if anomalies[3].Previous != 2500 {
+		t.Error("Anomalous values must not become the baseline", anomalies[3])
+	}
+
+	transport := client.HTTPClient.Transport
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(500, `{"type":"error","message":"fail"}`), nil
+	})
+	if err := push(4000); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	client.HTTPClient.Transport = transport
+	if err := push(9000); err != nil || len(anomalies) != 5 || anomalies[4].Previous != 2500 {
+		t.Error("Values of failed pushes must not become the baseline", err, anomalies)
+	}
+}
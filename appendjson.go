@@ -0,0 +1,261 @@
+package databox
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// AppendJSON appends the JSON object of the KPI, as sent in the push
+// request, to dst and returns the extended buffer. Unlike ToJSONData it
+// builds no intermediate map, so reusing dst makes encoding of KPIs with
+// string, bool and numeric attributes allocation free. Keys are not sorted.
+// NaN and infinite values are encoded as null.
+func (kpi *KPI) AppendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	first := true
+	field := func(name string, metric bool) {
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		if metric {
+			dst = appendJSONString(dst, "$", name)
+		} else {
+			dst = appendJSONString(dst, "", name)
+		}
+		dst = append(dst, ':')
+	}
+
+	// Attributes are overwritten by the other fields, as in ToJSONData.
+	for key, value := range kpi.Attributes {
+		if kpi.shadows(key) {
+			continue
+		}
+		field(key, false)
+		dst = appendJSONValue(dst, value)
+	}
+	for key, value := range kpi.Metrics {
+		if kpi.Key != "" && key == kpi.Key {
+			continue
+		}
+		field(key, true)
+		dst = appendFloat32(dst, value)
+	}
+	if kpi.Key != "" {
+		field(kpi.Key, true)
+		dst = appendFloat32(dst, kpi.Value)
+	}
+	if kpi.Date != "" {
+		field("date", false)
+		dst = appendJSONString(dst, "", kpi.Date)
+	}
+	if kpi.Unit != "" {
+		field("unit", false)
+		dst = appendJSONString(dst, "", kpi.Unit)
+	}
+	return append(dst, '}')
+}
+
+// shadows reports whether the attribute key is overwritten by other field of
+// the KPI.
+func (kpi *KPI) shadows(key string) bool {
+	switch {
+	case key == "date":
+		return kpi.Date != ""
+	case key == "unit":
+		return kpi.Unit != ""
+	case len(key) > 0 && key[0] == '$':
+		if kpi.Key != "" && key[1:] == kpi.Key {
+			return true
+		}
+		_, ok := kpi.Metrics[key[1:]]
+		return ok
+	}
+	return false
+}
+
+// AppendPayload appends the push request body, {"data": [...], "meta": {...}},
+// of kpis to dst and returns the extended buffer. Meta is omitted if empty.
+func AppendPayload(dst []byte, kpis []KPI, meta map[string]interface{}) []byte {
+	dst = append(dst, `{"data":[`...)
+	for i := range kpis {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = kpis[i].AppendJSON(dst)
+	}
+	dst = append(dst, ']')
+	if len(meta) > 0 {
+		dst = append(dst, `,"meta":`...)
+		dst = appendJSONValue(dst, meta)
+	}
+	return append(dst, '}')
+}
+
+// AppendSerializer is a Serializer encoding the payload with AppendPayload.
+// Values are formatted with the fewest digits that round-trip the float32.
+// Unlike AppendPayload, it fails on NaN and infinite values.
+type AppendSerializer struct{}
+
+// Serialize implements Serializer.
+func (AppendSerializer) Serialize(kpis []KPI, meta map[string]interface{}) ([]byte, error) {
+	for _, kpi := range kpis {
+		if kpi.Key != "" && !isFinite(kpi.Value) {
+			return nil, fmt.Errorf("unsupported value %v of metric %q", kpi.Value, kpi.Key)
+		}
+		for key, value := range kpi.Metrics {
+			if !isFinite(value) {
+				return nil, fmt.Errorf("unsupported value %v of metric %q", value, key)
+			}
+		}
+	}
+	return AppendPayload(nil, kpis, meta), nil
+}
+
+func isFinite(value float32) bool {
+	return !math.IsNaN(float64(value)) && !math.IsInf(float64(value), 0)
+}
+
+// appendJSONValue appends value, falling back to encoding/json for types
+// without a fast path.
+func appendJSONValue(dst []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJSONString(dst, "", v)
+	case bool:
+		return strconv.AppendBool(dst, v)
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(dst, v, 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(v), 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(dst, v, 10)
+	case float32:
+		return appendFloat32(dst, v)
+	case float64:
+		return appendFloat(dst, v, 64)
+	case map[string]interface{}:
+		dst = append(dst, '{')
+		first := true
+		for key, value := range v {
+			if !first {
+				dst = append(dst, ',')
+			}
+			first = false
+			dst = appendJSONString(dst, "", key)
+			dst = append(dst, ':')
+			dst = appendJSONValue(dst, value)
+		}
+		return append(dst, '}')
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return append(dst, "null"...)
+	}
+	return append(dst, encoded...)
+}
+
+func appendFloat32(dst []byte, value float32) []byte {
+	return appendFloat(dst, float64(value), 32)
+}
+
+// exactFloat64 converts value to the float64 of its shortest decimal
+// representation, the one the value is serialized with.
+func exactFloat64(value float32) float64 {
+	var buf [32]byte
+	f, err := strconv.ParseFloat(string(strconv.AppendFloat(buf[:0], float64(value), 'g', -1, 32)), 64)
+	if err != nil {
+		return float64(value)
+	}
+	return f
+}
+
+// appendFloat formats the value with the shortest representation that
+// round-trips at bitSize, the same way encoding/json does.
+func appendFloat(dst []byte, value float64, bitSize int) []byte {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return append(dst, "null"...)
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, value, format, -1, bitSize)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends the quoted, escaped concatenation of prefix and s.
+func appendJSONString(dst []byte, prefix, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendEscaped(dst, prefix)
+	dst = appendEscaped(dst, s)
+	return append(dst, '"')
+}
+
+func appendEscaped(dst []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(dst, s[start:]...)
+}
@@ -0,0 +1,155 @@
+package databox
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var appendKPIs = []KPI{
+	{Key: "a", Value: 0.1, Date: "2015-01-01 09:00:00", Unit: "USD"},
+	{Key: "b", Value: 1e-7, Metrics: map[string]float32{"b": 2, "c": 3e21}},
+	{Key: "c", Value: -12, Attributes: map[string]interface{}{
+		"string": "\"quoted\"\n\x01< \xff",
+		"int":    42,
+		"float":  0.3,
+		"bool":   true,
+		"nil":    nil,
+		"slice":  []string{"x"},
+		"map":    map[string]interface{}{"nested": 1},
+		"date":   "shadowed",
+		"$c":     "shadowed",
+	}},
+}
+
+func TestKPI_AppendJSON(t *testing.T) {
+	t.Parallel()
+
+	for _, kpi := range appendKPIs {
+		want, err := json.Marshal(kpi.ToJSONData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := kpi.AppendJSON([]byte("prefix"))
+		if string(got[:6]) != "prefix" {
+			t.Fatal("Must append to dst")
+		}
+		assertJSONEqual(t, got[6:], want)
+	}
+}
+
+func TestAppendPayload(t *testing.T) {
+	t.Parallel()
+
+	meta := map[string]interface{}{"ensure_unique": true}
+	want, err := JSONSerializer{}.Serialize(appendKPIs, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := AppendSerializer{}.Serialize(appendKPIs, meta)
+	assertJSONEqual(t, got, want)
+
+	if got := AppendPayload(nil, nil, nil); string(got) != `{"data":[]}` {
+		t.Error("Empty payload mismatch", string(got))
+	}
+}
+
+func TestKPI_AppendJSONAllocs(t *testing.T) {
+	kpi := KPI{Key: "a", Value: 1.5, Date: "2015-01-01", Attributes: map[string]interface{}{"host": "web-1"}}
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = kpi.AppendJSON(buf[:0])
+	})
+	if allocs != 0 {
+		t.Error("Encoding to a reused buffer must not allocate", allocs)
+	}
+}
+
+func assertJSONEqual(t *testing.T, got, want []byte) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal("Invalid JSON", string(got), err)
+	}
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func BenchmarkKPI_AppendJSON(b *testing.B) {
+	kpi := appendKPIs[0]
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = kpi.AppendJSON(buf[:0])
+	}
+}
+
+func BenchmarkKPI_ToJSONData(b *testing.B) {
+	kpi := appendKPIs[0]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = json.Marshal(kpi.ToJSONData())
+	}
+}
+
+func TestSerializeFloats(t *testing.T) {
+	t.Parallel()
+
+	tests := map[float32]string{
+		0.1:       "0.1",
+		0.2:       "0.2",
+		1.1:       "1.1",
+		3.14:      "3.14",
+		19.99:     "19.99",
+		-0.5:      "-0.5",
+		100:       "100",
+		123456.78: "123456.78",
+		16777216:  "16777216",
+		1e-7:      "1e-7",
+		3e21:      "3e+21",
+	}
+	for _, serializer := range []Serializer{JSONSerializer{}, AppendSerializer{}} {
+		for value, want := range tests {
+			payload, err := serializer.Serialize([]KPI{{Key: "a", Value: value}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(payload); got != `{"data":[{"$a":`+want+`}]}` {
+				t.Errorf("%T %v: got %s, want %s", serializer, value, got, want)
+			}
+		}
+	}
+	for value := range tests {
+		if got := exactFloat64(value); strconv.FormatFloat(got, 'g', -1, 64) != strconv.FormatFloat(float64(value), 'g', -1, 32) {
+			t.Errorf("%v: widened to %v", value, got)
+		}
+	}
+
+	nan := float32(math.NaN())
+	if _, err := (AppendSerializer{}).Serialize([]KPI{{Metrics: map[string]float32{"a": nan}}}, nil); err == nil {
+		t.Error("NaN must not be serialized")
+	}
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	kpis := make([]KPI, 100)
+	for i := range kpis {
+		kpis[i] = KPI{Key: "sales", Value: float32(i) / 10, Date: "2015-01-01", Attributes: map[string]interface{}{"shop": "eshop"}}
+	}
+	for _, serializer := range []Serializer{JSONSerializer{}, AppendSerializer{}} {
+		b.Run(fmt.Sprintf("%T", serializer), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = serializer.Serialize(kpis, nil)
+			}
+		})
+	}
+}
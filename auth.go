@@ -0,0 +1,32 @@
+package databox
+
+import "net/http"
+
+// Auth puts the push token to a request.
+type Auth func(request *http.Request, pushToken string)
+
+// BasicAuth sends the token as the basic auth user name. It's the default.
+func BasicAuth(request *http.Request, pushToken string) {
+	request.SetBasicAuth(pushToken, "")
+}
+
+// BearerAuth sends the token with the Bearer scheme of the Authorization
+// header.
+func BearerAuth(request *http.Request, pushToken string) {
+	request.Header.Set("Authorization", "Bearer "+pushToken)
+}
+
+// HeaderAuth returns an Auth sending the token in the header of the name,
+// e.g. for proxies stripping the Authorization header.
+func HeaderAuth(name string) Auth {
+	return func(request *http.Request, pushToken string) {
+		request.Header.Set(name, pushToken)
+	}
+}
+
+// WithAuth sets the way the push token is sent.
+func WithAuth(auth Auth) Option {
+	return func(c *Client) {
+		c.Auth = auth
+	}
+}
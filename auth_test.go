@@ -0,0 +1,37 @@
+package databox
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		auth   Auth
+		header string
+		want   string
+	}{
+		"basic":  {nil, "Authorization", "Basic dG9rZW46"},
+		"bearer": {BearerAuth, "Authorization", "Bearer token"},
+		"header": {HeaderAuth("X-Databox-Token"), "X-Databox-Token", "token"},
+	}
+	for name, test := range tests {
+		var got http.Header
+		client := NewClient("token", WithAuth(test.auth))
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			got = r.Header
+			return jsonResponse(200, `{"id":"someRandomId"}`), nil
+		})
+		if _, err := client.Push(&KPI{Key: "a", Value: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if got.Get(test.header) != test.want {
+			t.Errorf("%s: got %q", name, got.Get(test.header))
+		}
+		if test.header != "Authorization" && got.Get("Authorization") != "" {
+			t.Errorf("%s: Authorization must not be sent", name)
+		}
+	}
+}
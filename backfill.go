@@ -0,0 +1,97 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultBackfillChunk is the number of KPIs pushed in one request when
+// Backfiller.ChunkSize is not set.
+const defaultBackfillChunk = 100
+
+// BackfillProgress describes the state of a backfill after a chunk is
+// pushed.
+type BackfillProgress struct {
+	// Done is the number of timestamps whose KPIs were pushed, out of Total.
+	Done, Total int
+	// KPIs is the number of KPIs pushed so far.
+	KPIs int
+	// Requests is the number of push requests sent so far.
+	Requests int
+	// Elapsed is the time since the backfill started.
+	Elapsed time.Duration
+}
+
+// Backfiller rebuilds the history of metrics, e.g. the last 90 days after
+// fixing a bug in their computation.
+type Backfiller struct {
+	Client *Client
+	// ChunkSize is the maximum number of KPIs pushed in one request. Zero
+	// means 100.
+	ChunkSize int
+	// Progress, if set, is called after every pushed chunk.
+	Progress func(BackfillProgress)
+}
+
+// Backfill calls values for every timestamp from from up to to, inclusive,
+// stepping by interval, and pushes the returned KPIs in chunks with
+// ensure_unique. KPIs without a date get the timestamp in DateTimeTZFormat.
+// On error, the timestamps reported as Done by Progress were pushed.
+func (b *Backfiller) Backfill(ctx context.Context, from, to time.Time, interval time.Duration, values func(ctx context.Context, t time.Time) ([]KPI, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s", interval)
+	}
+	chunkSize := b.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBackfillChunk
+	}
+
+	started := time.Now()
+	progress := BackfillProgress{}
+	if !to.Before(from) {
+		progress.Total = int(to.Sub(from)/interval) + 1
+	}
+	var chunk []KPI
+	var chunkDone int
+	flush := func() error {
+		if len(chunk) > 0 {
+			if _, err := b.Client.InsertAll(ctx, chunk, true); err != nil {
+				return fmt.Errorf("pushing %d KPIs: %w", len(chunk), err)
+			}
+			progress.KPIs += len(chunk)
+			progress.Requests++
+		}
+		progress.Done += chunkDone
+		progress.Elapsed = time.Since(started)
+		chunk, chunkDone = nil, 0
+		if b.Progress != nil {
+			return b.reportProgress(progress)
+		}
+		return nil
+	}
+
+	for i := 0; i < progress.Total; i++ {
+		t := from.Add(time.Duration(i) * interval)
+		kpis, err := backfillValues(ctx, values, t)
+		if err != nil {
+			return fmt.Errorf("getting values of %s: %w", t.Format(DateTimeTZFormat), err)
+		}
+		for _, kpi := range kpis {
+			if kpi.Date == "" {
+				kpi.Date = t.Format(DateTimeTZFormat)
+			}
+			chunk = append(chunk, kpi)
+		}
+		chunkDone++
+		if len(chunk) >= chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if chunkDone > 0 {
+		return flush()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	var progress []BackfillProgress
+	backfiller := &Backfiller{Client: client, ChunkSize: 4, Progress: func(p BackfillProgress) { progress = append(progress, p) }}
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 4)
+	err := backfiller.Backfill(context.Background(), from, to, 24*time.Hour, func(ctx context.Context, t time.Time) ([]KPI, error) {
+		return []KPI{{Key: "revenue", Value: float32(t.Day())}, {Key: "orders", Value: 1, Date: t.Format(DateFormat)}}, nil
+	})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+
+	if len(requests) != 3 || len(requests[0].Data) != 4 || len(requests[2].Data) != 2 {
+		t.Fatal("KPIs must be pushed in chunks", requests)
+	}
+	for _, request := range requests {
+		if request.Meta["ensure_unique"] != true {
+			t.Error("Backfill must be pushed with ensure_unique", request.Meta)
+		}
+	}
+	if requests[0].Data[0]["date"] != "2020-01-01 00:00:00+00:00" || requests[2].Data[1]["date"] != "2020-01-05" {
+		t.Error("Unexpected dates", requests[0].Data[0], requests[2].Data[1])
+	}
+	last := progress[len(progress)-1]
+	if len(progress) != 3 || last.Done != 5 || last.Total != 5 || last.KPIs != 10 || last.Requests != 3 {
+		t.Error("Unexpected progress", progress)
+	}
+
+	errValues := errors.New("no data")
+	err = backfiller.Backfill(context.Background(), from, to, 24*time.Hour, func(ctx context.Context, t time.Time) ([]KPI, error) {
+		return nil, errValues
+	})
+	if !errors.Is(err, errValues) {
+		t.Error("Expected error of values", err)
+	}
+}
@@ -0,0 +1,109 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Batch collects KPIs and sends them in a single request on Flush. It doesn't
+// start any goroutines, so it's suitable for short-lived environments. Batch
+// is safe for concurrent use.
+type Batch struct {
+	client *Client
+
+	mu   sync.Mutex
+	kpis []KPI
+}
+
+// NewBatch returns an empty batch sending its KPIs with c.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// Add appends kpis to the batch.
+func (b *Batch) Add(kpis ...KPI) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.kpis = append(b.kpis, kpis...)
+	b.client.Stats.addQueued(len(kpis))
+}
+
+// Len returns the number of KPIs waiting in the batch.
+func (b *Batch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.kpis)
+}
+
+// Flush sends all KPIs collected so far in one request and empties the
+// batch. Nothing is sent if the batch is empty. When the request fails, the
+// KPIs are put back to the batch, so the flush can be repeated.
+func (b *Batch) Flush(ctx context.Context) (*ResponseStatus, error) {
+	b.mu.Lock()
+	kpis := b.kpis
+	b.kpis = nil
+	b.mu.Unlock()
+
+	if len(kpis) == 0 {
+		return nil, nil
+	}
+	responseStatus, err := b.client.InsertAll(ctx, kpis, false)
+	if err != nil {
+		b.mu.Lock()
+		b.kpis = append(kpis, b.kpis...)
+		b.mu.Unlock()
+		return nil, err
+	}
+	b.client.Stats.addQueued(-len(kpis))
+	return responseStatus, nil
+}
+
+// BatchCloseReport accounts for the KPIs of a batch at Close.
+type BatchCloseReport struct {
+	// Pushed is the number of KPIs pushed by the final flush.
+	Pushed int
+	// Spilled is the number of KPIs written to the spill.
+	Spilled int
+	// Left is the number of KPIs neither pushed nor spilled. They stay in
+	// the batch.
+	Left int
+}
+
+// Close flushes the batch for the last time, within the deadline of ctx. If
+// the flush fails and spill is not nil, the remaining KPIs are written to
+// spill as one payload line with ensure_unique meta, after the client's
+// transforms, so they can be pushed later with Replayer. The error of the
+// flush is returned unless all KPIs were spilled.
+func (b *Batch) Close(ctx context.Context, spill io.Writer) (BatchCloseReport, error) {
+	n := b.Len()
+	_, err := b.Flush(ctx)
+	if err == nil {
+		return BatchCloseReport{Pushed: n}, nil
+	}
+	if spill == nil {
+		return BatchCloseReport{Left: b.Len()}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	report := BatchCloseReport{Left: len(b.kpis)}
+	// The deadline has likely passed, but the values of ctx, e.g. attributes,
+	// still apply.
+	spillCtx := context.WithoutCancel(ctx)
+	kpis, _, prepareErr := b.client.prepare(spillCtx, b.kpis)
+	if prepareErr != nil {
+		return report, fmt.Errorf("%w; spilling: %v", err, prepareErr)
+	}
+	payload, spillErr := b.client.serializeKPIs(spillCtx, kpis, true)
+	if spillErr == nil {
+		_, spillErr = spill.Write(append(payload, '\n'))
+	}
+	if spillErr != nil {
+		return report, fmt.Errorf("%w; spilling: %v", err, spillErr)
+	}
+	b.client.Stats.addQueued(-len(b.kpis))
+	b.kpis = nil
+	return BatchCloseReport{Spilled: report.Left}, nil
+}
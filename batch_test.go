@@ -0,0 +1,101 @@
+package databox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestBatchFlush(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	statusCode := 500
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(statusCode, `{"id":"someRandomId","type":"error","message":"fail"}`), nil
+	})
+
+	batch := client.NewBatch()
+	if _, err := batch.Flush(context.Background()); err != nil || len(requests) != 0 {
+		t.Error("Empty batch must not be sent", err)
+	}
+
+	batch.Add(KPI{Key: "a", Value: 1}, KPI{Key: "b", Value: 2})
+	if _, err := batch.Flush(context.Background()); err == nil {
+		t.Error("This should not be \"ok\"")
+	}
+	if batch.Len() != 2 {
+		t.Error("Failed KPIs must be kept in batch, got", batch.Len())
+	}
+
+	statusCode = 200
+	batch.Add(KPI{Key: "c", Value: 3})
+	if _, err := batch.Flush(context.Background()); err != nil {
+		t.Error("Must be nil", err)
+	}
+	if batch.Len() != 0 {
+		t.Error("Batch must be empty after flush")
+	}
+	if len(requests) != 2 || len(requests[1].Data) != 3 {
+		t.Error("All KPIs must be sent in one request", requests)
+	}
+}
+
+func TestBatchClose(t *testing.T) {
+	t.Parallel()
+
+	var replayed []KPIWrap
+	failing := true
+	client := NewClient(getToken(), WithKeyPrefix("app."))
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if failing {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		replayed = append(replayed, wrap)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	batch := client.NewBatch()
+	batch.Add(KPI{Key: "a", Value: 1}, KPI{Key: "b", Value: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := batch.Close(ctx, nil)
+	if err == nil || report != (BatchCloseReport{Left: 2}) || batch.Len() != 2 {
+		t.Fatal("KPIs must be left behind without spill", report, err)
+	}
+
+	var spill bytes.Buffer
+	report, err = batch.Close(ctx, &spill)
+	if err != nil || report != (BatchCloseReport{Spilled: 2}) || batch.Len() != 0 {
+		t.Fatal("KPIs must be spilled", report, err)
+	}
+
+	failing = false
+	replayer := &Replayer{Client: NewClient(getToken())}
+	replayer.Client.HTTPClient.Transport = client.HTTPClient.Transport
+	if err := replayer.ReplayDir(context.Background(), fstest.MapFS{"spill.ndjson": {Data: spill.Bytes()}}, "*.ndjson"); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(replayed) != 1 || len(replayed[0].Data) != 2 || replayed[0].Data[0]["$app.a"] != 1.0 || replayed[0].Meta["ensure_unique"] != true {
+		t.Error("Spilled KPIs must be replayable", replayed)
+	}
+
+	batch.Add(KPI{Key: "c", Value: 3})
+	if report, err := batch.Close(context.Background(), &spill); err != nil || report != (BatchCloseReport{Pushed: 1}) {
+		t.Error("KPIs must be pushed", report, err)
+	}
+}
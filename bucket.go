@@ -0,0 +1,64 @@
+package databox
+
+import "time"
+
+// Bucket is a calendar period KPI dates are aligned to.
+type Bucket int
+
+const (
+	// BucketDay starts at midnight.
+	BucketDay Bucket = iota
+	// BucketWeek starts at midnight of Monday, as ISO 8601 weeks.
+	BucketWeek
+	// BucketMonth starts at midnight of the first day of the month.
+	BucketMonth
+	// BucketQuarter starts at midnight of the first day of January, April,
+	// July or October.
+	BucketQuarter
+	// BucketYear starts at midnight of the first day of January.
+	BucketYear
+)
+
+func (b Bucket) String() string {
+	switch b {
+	case BucketWeek:
+		return "week"
+	case BucketMonth:
+		return "month"
+	case BucketQuarter:
+		return "quarter"
+	case BucketYear:
+		return "year"
+	}
+	return "day"
+}
+
+// Truncate returns the start of the bucket containing t in the time zone
+// loc, e.g. the warehouse's one. A nil loc means UTC.
+func (b Bucket) Truncate(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch b {
+	case BucketWeek:
+		// Monday is the first day, Sunday the seventh.
+		day -= (int(t.Weekday()) + 6) % 7
+	case BucketMonth:
+		day = 1
+	case BucketQuarter:
+		month, day = month-(month-1)%3, 1
+	case BucketYear:
+		month, day = time.January, 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// Date returns the start of the bucket containing t in loc, formatted as KPI
+// date in DateTimeTZFormat. The offset keeps the bucket in its time zone,
+// while a date in DateFormat is taken as midnight UTC and may shift
+// dashboards by a day.
+func (b Bucket) Date(t time.Time, loc *time.Location) string {
+	return b.Truncate(t, loc).Format(DateTimeTZFormat)
+}
@@ -0,0 +1,39 @@
+package databox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skip("time zone database is not available", err)
+	}
+	// Thursday 2020-05-14 23:30 UTC is already Friday in Prague.
+	at := time.Date(2020, 5, 14, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		bucket Bucket
+		loc    *time.Location
+		want   string
+	}{
+		{BucketDay, nil, "2020-05-14 00:00:00+00:00"},
+		{BucketDay, prague, "2020-05-15 00:00:00+02:00"},
+		{BucketWeek, prague, "2020-05-11 00:00:00+02:00"},
+		{BucketMonth, prague, "2020-05-01 00:00:00+02:00"},
+		{BucketQuarter, prague, "2020-04-01 00:00:00+02:00"},
+		{BucketYear, prague, "2020-01-01 00:00:00+01:00"},
+	}
+	for _, test := range tests {
+		if got := test.bucket.Date(at, test.loc); got != test.want {
+			t.Errorf("%s in %v: got %s, want %s", test.bucket, test.loc, got, test.want)
+		}
+	}
+
+	sunday := time.Date(2020, 5, 17, 12, 0, 0, 0, time.UTC)
+	if got := BucketWeek.Date(sunday, nil); got != "2020-05-11 00:00:00+00:00" {
+		t.Error("Sunday must belong to the week starting on Monday", got)
+	}
+}
@@ -0,0 +1,72 @@
+package databox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+)
+
+// Codec compresses request bodies. Codecs not in the standard library, e.g.
+// zstd, can be plugged in by implementing it in the application:
+//
+//	type zstdCodec struct{ encoder *zstd.Encoder } // github.com/klauspost/compress/zstd
+//
+//	func (zstdCodec) Encoding() string { return "zstd" }
+//
+//	func (c zstdCodec) Compress(dst, data []byte) ([]byte, error) {
+//		return c.encoder.EncodeAll(data, dst), nil
+//	}
+type Codec interface {
+	// Encoding is the Content-Encoding of the compressed bodies.
+	Encoding() string
+	// Compress appends the compressed data to dst and returns the extended
+	// buffer.
+	Compress(dst, data []byte) ([]byte, error)
+}
+
+// GzipCodec compresses request bodies with gzip.
+type GzipCodec struct {
+	// Level is the compression level, see compress/gzip. Zero means
+	// gzip.DefaultCompression; gzip.BestSpeed saves CPU on large backfills.
+	Level int
+}
+
+var _ Codec = GzipCodec{}
+
+// Encoding implements Codec.
+func (GzipCodec) Encoding() string {
+	return "gzip"
+}
+
+// Compress implements Codec.
+func (c GzipCodec) Compress(dst, data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	buf := bytes.NewBuffer(dst)
+	w, err := gzip.NewWriterLevel(buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compress encodes the request body with c.Codec, if set, and returns the
+// Content-Encoding of the result, empty if the body is not compressed.
+func (c *Client) compress(payload []byte) ([]byte, string, error) {
+	if c.Codec == nil || len(payload) == 0 {
+		return payload, "", nil
+	}
+	compressed, err := c.Codec.Compress(nil, payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("compressing request body: %w", err)
+	}
+	return compressed, c.Codec.Encoding(), nil
+}
@@ -0,0 +1,65 @@
+package databox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// reverseCodec is a trivial Codec reversing the body.
+type reverseCodec struct{}
+
+func (reverseCodec) Encoding() string { return "x-reverse" }
+
+func (reverseCodec) Compress(dst, data []byte) ([]byte, error) {
+	for i := len(data) - 1; i >= 0; i-- {
+		dst = append(dst, data[i])
+	}
+	return dst, nil
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	var encoding string
+	var body []byte
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		encoding = r.Header.Get("Content-Encoding")
+		body, _ = io.ReadAll(r.Body)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	kpis := []KPI{{Key: "a", Value: 1}}
+	want, _ := client.serializeKPIs(context.Background(), kpis, false)
+
+	client.Codec = GzipCodec{Level: gzip.BestSpeed}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("Body must be gzipped", err)
+	}
+	got, _ := io.ReadAll(reader)
+	if encoding != "gzip" || !bytes.Equal(got, want) {
+		t.Errorf("Unexpected %q body %s", encoding, got)
+	}
+
+	client.Codec = reverseCodec{}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	got, _ = reverseCodec{}.Compress(nil, body)
+	if encoding != "x-reverse" || !json.Valid(got) || !bytes.Equal(got, want) {
+		t.Errorf("Unexpected %q body %s", encoding, got)
+	}
+
+	client.Codec = GzipCodec{Level: 42}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err == nil {
+		t.Error("Invalid level must fail")
+	}
+}
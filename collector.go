@@ -0,0 +1,58 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Collector produces KPIs on demand, e.g. by querying a database or scraping
+// an endpoint.
+type Collector interface {
+	// Collect returns the current KPIs.
+	Collect(ctx context.Context) ([]KPI, error)
+}
+
+// CollectorFunc is an adapter to allow the use of ordinary functions as
+// Collectors.
+type CollectorFunc func(ctx context.Context) ([]KPI, error)
+
+// Collect calls f(ctx).
+func (f CollectorFunc) Collect(ctx context.Context) ([]KPI, error) {
+	return f(ctx)
+}
+
+// CollectEvery runs collector immediately and then every interval, and pushes
+// the collected KPIs. It blocks until ctx is cancelled and returns the
+// context's error. Errors of individual rounds are passed to onError, which
+// may be nil.
+func (c *Client) CollectEvery(ctx context.Context, interval time.Duration, collector Collector, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.collect(ctx, collector); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect runs one collection round.
+func (c *Client) collect(ctx context.Context, collector Collector) error {
+	kpis, err := runCollector(ctx, collector)
+	if err != nil {
+		return fmt.Errorf("collecting KPIs: %w", err)
+	}
+	if len(kpis) == 0 {
+		return nil
+	}
+	if _, err := c.InsertAll(ctx, kpis, false); err != nil {
+		return fmt.Errorf("pushing %d collected KPIs: %w", len(kpis), err)
+	}
+	return nil
+}
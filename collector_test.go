@@ -0,0 +1,44 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCollectEvery(t *testing.T) {
+	t.Parallel()
+
+	pushed := make(chan struct{}, 10)
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		pushed <- struct{}{}
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	var rounds int
+	collector := CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+		rounds++
+		if rounds == 2 {
+			return nil, errors.New("database is down")
+		}
+		return []KPI{{Key: "users", Value: float32(rounds)}}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 10)
+	done := make(chan error)
+	go func() {
+		done <- client.CollectEvery(ctx, time.Millisecond, collector, func(err error) { errs <- err })
+	}()
+
+	<-pushed
+	<-errs
+	<-pushed
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled", err)
+	}
+}
@@ -0,0 +1,74 @@
+package databox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CompactionReport accounts for the data removed by the client's pipeline
+// from a push, so users can verify it isn't eating data unexpectedly. See
+// Client.OnCompaction.
+type CompactionReport struct {
+	// InputKPIs and OutputKPIs are the numbers of KPIs entering and leaving
+	// the pipeline.
+	InputKPIs, OutputKPIs int
+	// InputValues and OutputValues are the numbers of metric values of the
+	// KPIs. DedupCache and Downsampler filter the values individually.
+	InputValues, OutputValues int
+	// Removed is the number of values removed by the stages which ran:
+	// "transforms", "dedup" and "downsample". It's negative if the stage
+	// added values, e.g. a transform splitting KPIs.
+	Removed map[string]int
+}
+
+func (r CompactionReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d KPIs (%d values) -> %d KPIs (%d values)", r.InputKPIs, r.InputValues, r.OutputKPIs, r.OutputValues)
+	stages := make([]string, 0, len(r.Removed))
+	for stage := range r.Removed {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		fmt.Fprintf(&b, ", %s removed %d", stage, r.Removed[stage])
+	}
+	return b.String()
+}
+
+// compaction builds a CompactionReport while the KPIs pass the pipeline.
+type compaction struct {
+	report CompactionReport
+	values int
+}
+
+func newCompaction(kpis []KPI) *compaction {
+	values := countValues(kpis)
+	return &compaction{
+		report: CompactionReport{InputKPIs: len(kpis), InputValues: values, Removed: make(map[string]int)},
+		values: values,
+	}
+}
+
+// stage records the KPIs left by the stage.
+func (c *compaction) stage(name string, kpis []KPI) {
+	values := countValues(kpis)
+	c.report.Removed[name] = c.values - values
+	c.values = values
+}
+
+// done returns the report of the pipeline output.
+func (c *compaction) done(kpis []KPI) CompactionReport {
+	c.report.OutputKPIs = len(kpis)
+	c.report.OutputValues = countValues(kpis)
+	return c.report
+}
+
+// countValues returns the number of metric values of kpis.
+func countValues(kpis []KPI) int {
+	var n int
+	for _, kpi := range kpis {
+		n += len(kpiValues(kpi))
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package databox
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCompactionReport(t *testing.T) {
+	t.Parallel()
+
+	var reports []CompactionReport
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	client.Transforms = []Transform{TransformEach(func(kpi KPI) ([]KPI, error) {
+		if kpi.Key == "skip" {
+			return nil, nil
+		}
+		return []KPI{kpi}, nil
+	})}
+	client.DedupCache = &DedupCache{Window: time.Hour, Drop: true}
+	client.Downsampler = &Downsampler{Window: time.Hour}
+	client.OnCompaction = func(report CompactionReport) {
+		reports = append(reports, report)
+	}
+
+	kpis := []KPI{
+		{Key: "a", Value: 1, Metrics: map[string]float32{"b": 2}},
+		{Key: "a", Value: 1},
+		{Key: "skip", Value: 3},
+	}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if _, err := client.InsertAll(context.Background(), kpis[:1], false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(reports) != 2 {
+		t.Fatal("Expected report of every push", reports)
+	}
+	if got := reports[0].String(); got != "3 KPIs (4 values) -> 1 KPIs (2 values), dedup removed 1, downsample removed 0, transforms removed 1" {
+		t.Error("Unexpected report", got)
+	}
+	// Values remembered by DedupCache after the first push.
+	if got := reports[1]; got.OutputKPIs != 0 || got.Removed["dedup"] != 2 {
+		t.Error("Unexpected report", got)
+	}
+}
@@ -0,0 +1,57 @@
+package databox
+
+import "context"
+
+type attributesKey struct{}
+
+// WithAttributes returns a copy of ctx carrying attributes, e.g. tenant or
+// job ID. The attributes are added to every KPI pushed with the context,
+// unless the KPI sets the attribute itself. Attributes already carried by ctx
+// are kept, unless overridden.
+func WithAttributes(ctx context.Context, attributes map[string]interface{}) context.Context {
+	merged := make(map[string]interface{})
+	for key, value := range AttributesFromContext(ctx) {
+		merged[key] = value
+	}
+	for key, value := range attributes {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, attributesKey{}, merged)
+}
+
+// AttributesFromContext returns the attributes carried by ctx. The returned
+// map must not be modified.
+func AttributesFromContext(ctx context.Context) map[string]interface{} {
+	attributes, _ := ctx.Value(attributesKey{}).(map[string]interface{})
+	return attributes
+}
+
+// withContextAttributes returns kpis with the attributes carried by ctx.
+func withContextAttributes(ctx context.Context, kpis []KPI) []KPI {
+	return withAttributes(kpis, AttributesFromContext(ctx), false)
+}
+
+// withAttributes returns copies of kpis with attributes added. KPI's own
+// attributes take precedence, unless override is set. The caller's KPIs
+// aren't modified.
+func withAttributes(kpis []KPI, attributes map[string]interface{}, override bool) []KPI {
+	if len(attributes) == 0 {
+		return kpis
+	}
+
+	merged := make([]KPI, len(kpis))
+	for i, kpi := range kpis {
+		kpiAttributes := make(map[string]interface{}, len(attributes)+len(kpi.Attributes))
+		for key, value := range kpi.Attributes {
+			kpiAttributes[key] = value
+		}
+		for key, value := range attributes {
+			if _, ok := kpiAttributes[key]; override || !ok {
+				kpiAttributes[key] = value
+			}
+		}
+		kpi.Attributes = kpiAttributes
+		merged[i] = kpi
+	}
+	return merged
+}
@@ -0,0 +1,35 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestContextAttributes(t *testing.T) {
+	t.Parallel()
+
+	var pushed KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &pushed)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	ctx := WithAttributes(context.Background(), map[string]interface{}{"tenant": "acme", "job": "import"})
+	ctx = WithAttributes(ctx, map[string]interface{}{"job": "export"})
+
+	kpi := &KPI{Key: "rows", Value: 10, Attributes: map[string]interface{}{"tenant": "globex"}}
+	if _, err := client.PushCtx(ctx, kpi); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(pushed.Data) != 1 || pushed.Data[0]["tenant"] != "globex" || pushed.Data[0]["job"] != "export" {
+		t.Error("Unexpected attributes", pushed.Data)
+	}
+	if len(kpi.Attributes) != 1 {
+		t.Error("KPI must not be modified", kpi.Attributes)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -25,11 +28,99 @@ const (
 	clientVersion = "2.1.0"
 )
 
+const (
+	// findPushFirstPage is the number of pushes FindPush requests first.
+	findPushFirstPage = 10
+	// findPushMaxDepth is the longest history FindPush asks for.
+	findPushMaxDepth = 640
+)
+
+// ErrPushNotFound is returned by FindPush when no push with the given ID is
+// present in the recent history.
+var ErrPushNotFound = errors.New("push not found")
+
 // Client struct holds push token and host to Databox service
 type Client struct {
+	// PushToken and PushHost are the initial settings, which can be replaced
+	// with Reload while the Client is in use.
 	PushToken  string
 	PushHost   string
 	HTTPClient *http.Client
+	// Auth puts the push token to requests. Nil means BasicAuth.
+	Auth Auth
+	// VerifyPushes enables confirmation of every push. After the push is
+	// accepted, it's looked up in the push history and compared with the sent
+	// KPIs. Mismatch is reported as ErrVerificationFailed.
+	VerifyPushes bool
+	// Backoff enables retries of failed requests. Nil disables retries.
+	Backoff Backoff
+	// RetryBudget limits retries across all requests sharing the budget. Nil
+	// means the retries are limited by Backoff only.
+	RetryBudget *RetryBudget
+	// NamingPolicy, if set, is enforced on all metric keys.
+	NamingPolicy *NamingPolicy
+	// Schema, if set, restricts pushed metrics to the registered ones.
+	Schema *Schema
+	// AnomalyGuard, if set, checks the values before they are pushed.
+	AnomalyGuard *AnomalyGuard
+	// UnitChecker, if set, warns about metrics changing their unit.
+	UnitChecker *UnitChecker
+	// DedupCache, if set, catches data points pushed twice.
+	DedupCache *DedupCache
+	// Downsampler, if set, suppresses unchanged values.
+	Downsampler *Downsampler
+	// Maintenance, if set, suppresses pushes during maintenance windows.
+	Maintenance *Maintenance
+	// Transforms rewrite the KPIs before they are checked and pushed. When
+	// transforms, DedupCache or Downsampler leave no KPI to push, nothing is
+	// sent and an empty ResponseStatus is returned.
+	Transforms []Transform
+	// OnCompaction, if set, is called with the CompactionReport of every
+	// push passing the pipeline, including pushes left with nothing to send.
+	OnCompaction func(CompactionReport)
+	// ReviewDiff, if set, is called before every push with the deltas of
+	// the values against the last pushed values of their metrics, see Diff.
+	// The last 10 pushes are searched. An error aborts the push.
+	ReviewDiff func(ctx context.Context, deltas []Delta) error
+	// Stats, if set, counts pushes, failures and retries, and measures
+	// request latencies.
+	Stats *Stats
+	// Receipts, if set, records every push request.
+	Receipts *ReceiptLog
+	// StrictDecoding rejects API responses with unknown fields. It's meant
+	// for staging, to detect changes of the API early.
+	StrictDecoding bool
+	// SignRequest, if set, is called before every request is sent, including
+	// retries. It can add headers required by egress gateways, e.g. HMAC
+	// signature of the body, which is available through Request.GetBody.
+	SignRequest func(*http.Request) error
+	// Serializer encodes the push payloads. Nil means JSONSerializer with
+	// encoding/json.
+	Serializer Serializer
+	// Codec, if set, compresses the bodies of push requests, e.g.
+	// GzipCodec. The API endpoint must accept the encoding.
+	Codec Codec
+	// Sequence, if set, returns the sequence number attached to every push
+	// in meta as "sequence", together with ensure_unique, so the server can
+	// deduplicate pushes across process restarts. The numbers must increase
+	// monotonically and be persisted by the caller, e.g. in a database
+	// sequence. Retries of a push keep its number.
+	Sequence func(ctx context.Context) (uint64, error)
+	// Precision rounds the values to the number of decimals of their metric
+	// key, e.g. 2 for currency and 0 for counts, when they are serialized.
+	// The empty key sets the precision of metrics not listed. Negative
+	// decimals round to tens, hundreds, and so on.
+	Precision map[string]int
+	// MaxResponseBytes, if set, caps the size of API responses read by the
+	// Client. Larger responses fail.
+	MaxResponseBytes int64
+	// Logger receives warnings. Nil disables logging.
+	Logger Logger
+
+	// dialer is used by the transport created by NewClient.
+	dialer *net.Dialer
+	// reloaded holds *Config set by Reload.
+	reloaded atomic.Value
 }
 
 // KPI struct holds information about item in push request
@@ -69,9 +160,47 @@ type ResponseStatus struct {
 
 // PushRequest struct holds information about Request returned from LastPush request
 type PushRequest struct {
-	Date   string   `json:"date"`
-	Body   KPIWrap  `json:"body"`
-	Errors []string `json:"errors"`
+	Date   string      `json:"date"`
+	Body   KPIWrap     `json:"body"`
+	Errors []PushError `json:"errors"`
+}
+
+// PushError describes an error of a push request returned from LastPush
+// request. The API reports errors either as objects, or as plain strings,
+// which are stored in Message.
+type PushError struct {
+	// Index is the index of the offending item in PushRequest.Body.Data, or
+	// -1 if the error isn't related to an item.
+	Index int `json:"index"`
+	// Field is the offending field of the item, if known.
+	Field string `json:"field,omitempty"`
+	// Message describes the error.
+	Message string `json:"message"`
+}
+
+// UnmarshalJSON decodes the error from an object or a plain string.
+func (e *PushError) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*e = PushError{Index: -1}
+		return json.Unmarshal(data, &e.Message)
+	}
+	type pushError PushError
+	decoded := pushError{Index: -1}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = PushError(decoded)
+	return nil
+}
+
+func (e PushError) Error() string {
+	switch {
+	case e.Index >= 0 && e.Field != "":
+		return fmt.Sprintf("item %d field %s: %s", e.Index, e.Field, e.Message)
+	case e.Index >= 0:
+		return fmt.Sprintf("item %d: %s", e.Index, e.Message)
+	}
+	return e.Message
 }
 
 // PushResponse struct holds information about Response returned from LastPush request
@@ -88,39 +217,85 @@ type LastPush struct {
 }
 
 // NewClient returns object for making calls against a Databox service.
-func NewClient(pushToken string) *Client {
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	// We use only one host: push.databox.com
-	transport.MaxIdleConnsPerHost = transport.MaxIdleConns
-
-	return &Client{
+func NewClient(pushToken string, opts ...Option) *Client {
+	c := &Client{
 		PushToken: pushToken,
 		PushHost:  apiURL,
-		HTTPClient: &http.Client{
-			Transport: transport,
-		},
 	}
+	c.HTTPClient = c.newHTTPClient()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c *Client) postRequest(ctx context.Context, path string, payload []byte) ([]byte, error) {
+// Close closes idle connections of the HTTP client, so their reading and
+// writing goroutines exit. The client starts no other goroutines: the
+// ...Every loops return once their context is cancelled, and Batch flushes
+// synchronously. A closed client can still be used; it opens new
+// connections as needed.
+func (c *Client) Close() error {
+	if c.HTTPClient != nil {
+		c.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Do sends a request to the Databox API with authentication and headers
+// applied, and retries it according to Backoff. It allows calling endpoints
+// not wrapped by the client. The path is relative to PushHost. The caller is
+// responsible for closing the response body.
+func (c *Client) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	request, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(request)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	config := c.config()
+	host := config.PushHost
+	if host == "" {
+		host = apiURL
+	}
 	userAgent := "databox-go/" + clientVersion
 	accept := "application/vnd.databox.v" + strings.Split(clientVersion, ".")[0] + "+json"
-	request, err := http.NewRequestWithContext(ctx, "POST", apiURL+path, bytes.NewBuffer(payload))
+	request, err := http.NewRequestWithContext(ctx, method, host+path, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request object: %w", err)
 	}
 	request.Header.Set("User-Agent", userAgent)
 	request.Header.Set("Accept", accept)
 	request.Header.Set("Content-Type", "application/json")
-	request.SetBasicAuth(c.PushToken, "")
+	auth := c.Auth
+	if auth == nil {
+		auth = BasicAuth
+	}
+	auth(request, config.PushToken)
+	return request, nil
+}
 
-	response, err := c.HTTPClient.Do(request)
+func (c *Client) postRequest(ctx context.Context, path string, payload []byte) ([]byte, error) {
+	payload, encoding, err := c.compress(payload)
+	if err != nil {
+		return nil, err
+	}
+	request, err := c.newRequest(ctx, "POST", path, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	if encoding != "" {
+		request.Header.Set("Content-Encoding", encoding)
+	}
+
+	response, err := c.do(request)
 	if err != nil {
 		return nil, fmt.Errorf("executing HTTP request: %w", err)
 	}
 	defer response.Body.Close()
 
-	data, err := ioutil.ReadAll(response.Body)
+	data, err := c.readResponse(response)
 	if err != nil {
 		return data, fmt.Errorf("reading response body: %w", err)
 	}
@@ -128,33 +303,27 @@ func (c *Client) postRequest(ctx context.Context, path string, payload []byte) (
 	if response.StatusCode < 200 || response.StatusCode > 299 {
 		var responseStatus = &ResponseStatus{}
 		if err := json.Unmarshal(data, &responseStatus); err != nil {
-			return nil, fmt.Errorf("can't unmarshal data[%s]: %w", string(data), err)
+			return nil, &APIError{StatusCode: response.StatusCode, Message: string(data)}
 		}
-		return nil, errors.New(responseStatus.Type + ": " + responseStatus.Message)
+		return nil, &APIError{StatusCode: response.StatusCode, Type: responseStatus.Type, Message: responseStatus.Message}
 	}
 
 	return data, nil
 }
 
 func (c *Client) getRequest(ctx context.Context, path string) ([]byte, error) {
-	userAgent := "databox-go/" + clientVersion
-	accept := "application/vnd.databox.v" + strings.Split(clientVersion, ".")[0] + "+json"
-	request, err := http.NewRequestWithContext(ctx, "GET", apiURL+path, nil)
+	request, err := c.newRequest(ctx, "GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request object: %w", err)
+		return nil, err
 	}
-	request.Header.Set("User-Agent", userAgent)
-	request.Header.Set("Accept", accept)
-	request.Header.Set("Content-Type", "application/json")
-	request.SetBasicAuth(c.PushToken, "")
 
-	response, err := c.HTTPClient.Do(request)
+	response, err := c.do(request)
 	if err != nil {
 		return nil, fmt.Errorf("executing HTTP request: %w", err)
 	}
 	defer response.Body.Close()
 
-	data, err := ioutil.ReadAll(response.Body)
+	data, err := c.readResponse(response)
 	if err != nil {
 		return nil, fmt.Errorf("reading response body: %w", err)
 	}
@@ -172,12 +341,12 @@ func (c *Client) LastPushesCtx(ctx context.Context, n int) ([]LastPush, error) {
 	path := fmt.Sprintf("/lastpushes?limit=%d", n)
 	response, err := c.getRequest(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("requesting /lastpushes from API: %w", err)
+		return nil, c.redactError(fmt.Errorf("requesting /lastpushes from API: %w", err))
 	}
 
 	lastPushes := make([]LastPush, 0)
-	if err := json.Unmarshal(response, &lastPushes); err != nil {
-		return nil, fmt.Errorf("can't unmarshal response[%s]: %w", string(response), err)
+	if err := c.decodeResponse(response, &lastPushes); err != nil {
+		return nil, c.redactError(fmt.Errorf("can't unmarshal response[%s]: %w", string(response), err))
 	}
 
 	return lastPushes, nil
@@ -201,6 +370,27 @@ func (c *Client) LastPushCtx(ctx context.Context) (LastPush, error) {
 	return lastPushes[0], nil
 }
 
+// FindPush returns the push whose response ID equals id. The ID is the one
+// returned in ResponseStatus by Push or InsertAll. The history is requested in
+// pages of growing size, until the push is found or there are no older pushes.
+func (c *Client) FindPush(ctx context.Context, id string) (LastPush, error) {
+	for limit := findPushFirstPage; limit <= findPushMaxDepth; limit *= 2 {
+		lastPushes, err := c.LastPushesCtx(ctx, limit)
+		if err != nil {
+			return LastPush{}, err
+		}
+		for _, lastPush := range lastPushes {
+			if lastPush.Response.Body.ID == id {
+				return lastPush, nil
+			}
+		}
+		if len(lastPushes) < limit {
+			break
+		}
+	}
+	return LastPush{}, fmt.Errorf("looking up push %q: %w", id, ErrPushNotFound)
+}
+
 // Push makes push request against Databox service.
 func (c *Client) Push(kpi *KPI) (*ResponseStatus, error) {
 	return c.PushCtx(context.Background(), kpi)
@@ -209,22 +399,7 @@ func (c *Client) Push(kpi *KPI) (*ResponseStatus, error) {
 // PushCtx makes push request against Databox service. It terminates the
 // request on context cancellation.
 func (c *Client) PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error) {
-	payload, err := serializeKPIs([]KPI{*kpi}, false)
-	if err != nil {
-		return nil, fmt.Errorf("preparing request: %w", err)
-	}
-
-	response, err := c.postRequest(ctx, "/", payload)
-	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
-	}
-
-	var responseStatus = &ResponseStatus{}
-	if err := json.Unmarshal(response, &responseStatus); err != nil {
-		return nil, fmt.Errorf("can't unmarshal respoonse[%s]: %w", string(response), err)
-	}
-
-	return responseStatus, nil
+	return c.InsertAll(ctx, []KPI{*kpi}, false)
 }
 
 // TODO document forcePush attribute
@@ -235,21 +410,74 @@ func (c *Client) PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error)
 // InsertAll makes insertAll request against Databox service. It terminates the
 // request on context cancellation.
 func (c *Client) InsertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
-	payload, err := serializeKPIs(kpis, forcePush)
+	ctx = startTrace(ctx)
+	if c.Maintenance != nil && !isDryRun(ctx) {
+		return c.Maintenance.insertAll(ctx, c, kpis, forcePush)
+	}
+	return c.insertAll(ctx, kpis, forcePush)
+}
+
+func (c *Client) insertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
+	n := len(kpis)
+	kpis, commit, err := c.prepare(ctx, kpis)
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun(ctx) {
+		trace(ctx, "dry run", kpis, "", nil)
+		return &ResponseStatus{}, nil
+	}
+	if len(kpis) == 0 && n > 0 {
+		return &ResponseStatus{}, nil
+	}
+	if c.ReviewDiff != nil {
+		if err := c.reviewDiff(ctx, kpis); err != nil {
+			trace(ctx, "review", nil, "", err)
+			return nil, err
+		}
+	}
+
+	payload, err := c.serializeKPIs(ctx, kpis, forcePush)
 	if err != nil {
-		return nil, fmt.Errorf("preparing request: %w", err)
+		err = fmt.Errorf("preparing request: %w", err)
+		trace(ctx, "push", nil, "", err)
+		return nil, err
+	}
+
+	start := time.Now()
+	responseStatus, err := c.send(ctx, payload)
+	err = c.timeoutError(ctx, err, "push", kpis, payload, start)
+	c.Stats.recordPush(kpis, err)
+	if c.Receipts != nil {
+		c.Receipts.record(kpis, responseStatus, err, c.Logger)
+	}
+	if err != nil {
+		trace(ctx, "push", nil, "", err)
+		return nil, err
+	}
+	trace(ctx, "push", kpis, responseStatus.ID, nil)
+	commit()
+
+	if c.VerifyPushes {
+		if err := c.verifyPush(ctx, responseStatus.ID, kpis); err != nil {
+			return responseStatus, err
+		}
 	}
 
+	return responseStatus, nil
+}
+
+// send posts the payload and decodes the response.
+func (c *Client) send(ctx context.Context, payload []byte) (*ResponseStatus, error) {
 	response, err := c.postRequest(ctx, "/", payload)
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		return nil, c.redactError(fmt.Errorf("sending request: %w", err))
 	}
 
 	var responseStatus = &ResponseStatus{}
-	if err := json.Unmarshal(response, &responseStatus); err != nil {
-		return nil, fmt.Errorf("can't unmarshal respoonse[%s]: %w", string(response), err)
+	if err := c.decodeResponse(response, &responseStatus); err != nil {
+		return nil, c.redactError(fmt.Errorf("can't unmarshal respoonse[%s]: %w", string(response), err))
 	}
-
 	return responseStatus, nil
 }
 
@@ -281,20 +509,29 @@ func (kpi *KPI) ToJSONData() map[string]interface{} {
 	return payload
 }
 
-// serializeKPIs traverse all kpis and return json representation
-func serializeKPIs(kpis []KPI, forcePush bool) ([]byte, error) {
-	wrap := KPIWrap{
-		Data: make([]map[string]interface{}, 0),
-	}
+// serializeKPIs traverse all kpis and return their representation encoded by
+// c.Serializer.
+func (c *Client) serializeKPIs(ctx context.Context, kpis []KPI, forcePush bool) ([]byte, error) {
+	var meta map[string]interface{}
 	if forcePush {
-		wrap.Meta = map[string]interface{}{
+		meta = map[string]interface{}{
 			"ensure_unique": true,
 		}
 	}
-
-	for _, kpi := range kpis {
-		wrap.Data = append(wrap.Data, kpi.ToJSONData())
+	if c.Sequence != nil {
+		sequence, err := c.sequence(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting sequence number: %w", err)
+		}
+		meta = map[string]interface{}{
+			"ensure_unique": true,
+			"sequence":      sequence,
+		}
 	}
 
-	return json.Marshal(wrap)
+	serializer := c.Serializer
+	if serializer == nil {
+		serializer = JSONSerializer{}
+	}
+	return serializer.Serialize(roundKPIs(kpis, c.Precision), meta)
 }
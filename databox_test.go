@@ -2,10 +2,15 @@ package databox
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -173,6 +178,112 @@ func TestWithAdditionalAttributes(t *testing.T) {
 	}
 }
 
+func TestFindPush(t *testing.T) {
+	t.Parallel()
+
+	var limits []int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		limits = append(limits, limit)
+		pushes := make([]string, 0, limit)
+		for i := 0; i < limit && i < 25; i++ {
+			pushes = append(pushes, fmt.Sprintf(`{"response":{"body":{"id":"push-%d"}}}`, i))
+		}
+		return jsonResponse(200, "["+strings.Join(pushes, ",")+"]"), nil
+	})
+
+	push, err := client.FindPush(context.Background(), "push-15")
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if push.Response.Body.ID != "push-15" {
+		t.Error("Wrong push found", push.Response.Body.ID)
+	}
+	if !reflect.DeepEqual(limits, []int{10, 20}) {
+		t.Error("Unexpected paging", limits)
+	}
+
+	limits = nil
+	if _, err := client.FindPush(context.Background(), "push-99"); !errors.Is(err, ErrPushNotFound) {
+		t.Error("Expected ErrPushNotFound", err)
+	}
+	if !reflect.DeepEqual(limits, []int{10, 20, 40}) {
+		t.Error("Paging must stop when history is exhausted", limits)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	var request *http.Request
+	client := NewClient(getToken())
+	client.PushHost = "https://push.example.com"
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		request = r
+		return jsonResponse(200, `{}`), nil
+	})
+
+	response, err := client.Do(context.Background(), http.MethodDelete, "/metrics/sales", nil)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	response.Body.Close()
+
+	if request.Method != http.MethodDelete || request.URL.String() != "https://push.example.com/metrics/sales" {
+		t.Error("Unexpected request", request.Method, request.URL)
+	}
+	if user, _, ok := request.BasicAuth(); !ok || user != getToken() {
+		t.Error("Request must be authenticated")
+	}
+	if request.Header.Get("User-Agent") != "databox-go/"+clientVersion || request.Header.Get("Accept") == "" {
+		t.Error("Headers must be set", request.Header)
+	}
+}
+
+func TestPushErrors(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = &responseMock{
+		resp: []byte(`[{"request":{"errors":[
+			"invalid date",
+			{"index":1,"field":"date","message":"invalid format"},
+			{"message":"too many items"}
+		]}}]`),
+	}
+
+	push, err := client.LastPush()
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []PushError{
+		{Index: -1, Message: "invalid date"},
+		{Index: 1, Field: "date", Message: "invalid format"},
+		{Index: -1, Message: "too many items"},
+	}
+	if !reflect.DeepEqual(push.Request.Errors, want) {
+		t.Errorf("got %+v, want %+v", push.Request.Errors, want)
+	}
+	if push.Request.Errors[1].Error() != "item 1 field date: invalid format" {
+		t.Error("Unexpected message", push.Request.Errors[1].Error())
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func jsonResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    statusCode,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
 type responseMock struct {
 	statusCode int
 	resp       []byte
@@ -189,3 +300,36 @@ func (r *responseMock) RoundTrip(_ *http.Request) (*http.Response, error) {
 	}
 	return resp, nil
 }
+
+func TestSequence(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	var last uint64 = 41
+	client.Sequence = func(ctx context.Context) (uint64, error) {
+		last++
+		return last, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Push(&KPI{Key: "a", Value: 1}); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+	}
+	for i, want := range []string{"42", "43"} {
+		if !strings.Contains(bodies[i], `"sequence":`+want) || !strings.Contains(bodies[i], `"ensure_unique":true`) {
+			t.Errorf("Sequence number must be in meta, got %s", bodies[i])
+		}
+	}
+
+	client.Sequence = func(ctx context.Context) (uint64, error) { return 0, errors.New("no sequence") }
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); err == nil || len(bodies) != 2 {
+		t.Error("Push without sequence number must fail", err)
+	}
+}
@@ -0,0 +1,73 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TimeoutError is returned when an operation runs out of time, either on the
+// deadline of its context or on the timeout of the HTTP client. It carries
+// what was being done, so the timeout can be triaged without a debugger.
+// It wraps the original error, so errors.Is(err, context.DeadlineExceeded)
+// keeps working.
+type TimeoutError struct {
+	// Op is the operation, e.g. "push".
+	Op string
+	// KPIs is the number of KPIs of the push.
+	KPIs int
+	// Bytes is the size of the request payload.
+	Bytes int
+	// Deadline is the deadline of the context, zero if it has none.
+	Deadline time.Time
+	// Timeout is the timeout of the HTTP client, zero if it has none.
+	Timeout time.Duration
+	// Elapsed is the time the operation ran before failing.
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	limit := "no deadline"
+	if !e.Deadline.IsZero() {
+		limit = "deadline " + e.Deadline.Format(time.RFC3339Nano)
+	}
+	if e.Timeout > 0 {
+		limit += fmt.Sprintf(", client timeout %s", e.Timeout)
+	}
+	return fmt.Sprintf("%s of %d KPIs (%d bytes) timed out after %s (%s): %v",
+		e.Op, e.KPIs, e.Bytes, e.Elapsed.Round(time.Millisecond), limit, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// isTimeout reports whether err is caused by a context deadline or a network
+// timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// timeoutError wraps err in *TimeoutError if it is a timeout of the push of
+// kpis encoded in payload, started at start.
+func (c *Client) timeoutError(ctx context.Context, err error, op string, kpis []KPI, payload []byte, start time.Time) error {
+	if err == nil || !isTimeout(err) {
+		return err
+	}
+	timeoutErr := &TimeoutError{
+		Op:      op,
+		KPIs:    len(kpis),
+		Bytes:   len(payload),
+		Elapsed: time.Since(start),
+		Err:     err,
+	}
+	timeoutErr.Deadline, _ = ctx.Deadline()
+	if c.HTTPClient != nil {
+		timeoutErr.Timeout = c.HTTPClient.Timeout
+	}
+	return timeoutErr
+}
@@ -0,0 +1,52 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutError(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+	kpis := []KPI{{Key: "a", Value: 1}, {Key: "b", Value: 2}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.InsertAll(ctx, kpis, false)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatal("Expected TimeoutError", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if timeoutErr.Op != "push" || timeoutErr.KPIs != 2 || timeoutErr.Bytes == 0 || !timeoutErr.Deadline.Equal(deadline) || timeoutErr.Elapsed < 20*time.Millisecond {
+		t.Error("Unexpected metadata", timeoutErr)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || KindOf(err) != KindNetwork {
+		t.Error("Must wrap the deadline error", err)
+	}
+	if !strings.Contains(err.Error(), "push of 2 KPIs") {
+		t.Error("Unexpected message", err)
+	}
+
+	client.HTTPClient.Timeout = 20 * time.Millisecond
+	_, err = client.InsertAll(context.Background(), kpis, false)
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 20*time.Millisecond || !timeoutErr.Deadline.IsZero() {
+		t.Error("Expected TimeoutError of the client timeout", err)
+	}
+
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	if _, err := client.InsertAll(context.Background(), kpis, false); errors.As(err, &timeoutErr) {
+		t.Error("Other errors must not be wrapped", err)
+	}
+}
@@ -0,0 +1,58 @@
+package databox
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DecodeError describes an API response that couldn't be decoded, or that
+// doesn't match the expected schema when Client.StrictDecoding is enabled.
+type DecodeError struct {
+	// Field is the path of the offending field, if known.
+	Field string
+	// Offset is the position in the response body where decoding failed.
+	Offset int64
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("decoding field %s at offset %d: %v", e.Field, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("decoding at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeResponse decodes the response body into v. In strict mode, unknown
+// fields and trailing data are errors, so schema drift of the API is detected
+// early. Decoding errors are returned as *DecodeError.
+func (c *Client) decodeResponse(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if c.StrictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(v)
+	if err == nil && c.StrictDecoding && decoder.More() {
+		err = errors.New("unexpected data after the response")
+	}
+	if err == nil {
+		return nil
+	}
+
+	decodeErr := &DecodeError{Offset: decoder.InputOffset(), Err: err}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		decodeErr.Field, decodeErr.Offset = typeErr.Field, typeErr.Offset
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		decodeErr.Offset = syntaxErr.Offset
+	}
+	return decodeErr
+}
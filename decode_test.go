@@ -0,0 +1,37 @@
+package databox
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestStrictDecoding(t *testing.T) {
+	t.Parallel()
+
+	response := `{"id":"someRandomId","status":"queued"}`
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, response), nil
+	})
+
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); err != nil {
+		t.Error("Unknown fields must be ignored by default", err)
+	}
+
+	client.StrictDecoding = true
+	var decodeErr *DecodeError
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); !errors.As(err, &decodeErr) {
+		t.Error("Expected DecodeError", err)
+	}
+
+	response = `[{"request":{"date":42}}]`
+	if _, err := client.LastPushes(1); !errors.As(err, &decodeErr) || decodeErr.Field != "0.request.date" || decodeErr.Offset == 0 {
+		t.Error("Expected DecodeError with field", err)
+	}
+
+	response = `{"id":"someRandomId"} {}`
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); !errors.As(err, &decodeErr) {
+		t.Error("Trailing data must fail", err)
+	}
+}
@@ -0,0 +1,104 @@
+package databox
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrDuplicate is returned when DedupCache rejects a push.
+var ErrDuplicate = errors.New("duplicate data point")
+
+// DedupCache remembers the data points pushed recently and catches exact
+// duplicates, i.e. the same metric key, date, attributes and value within
+// Window. It protects against upstream pipelines delivering an event twice.
+// DedupCache is safe for concurrent use.
+type DedupCache struct {
+	// Window is how long a pushed data point is remembered.
+	Window time.Duration
+	// Drop removes duplicates from the push. Otherwise a push containing a
+	// duplicate is rejected by ErrDuplicate.
+	Drop bool
+	// OnDuplicate, if set, is called for every duplicate.
+	OnDuplicate func(DataPoint)
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// filter returns kpis without duplicates, or ErrDuplicate. The returned
+// commit function remembers the data points; it's meant to be called after a
+// successful push.
+func (d *DedupCache) filter(kpis []KPI) ([]KPI, func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.now != nil {
+		now = d.now()
+	}
+	if now.Sub(d.lastSweep) > d.Window {
+		for id, at := range d.seen {
+			if now.Sub(at) >= d.Window {
+				delete(d.seen, id)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	var duplicates []DataPoint
+	pushed := make(map[string]bool)
+	duplicate := func(kpi *KPI, key string, value float32) bool {
+		id := fmt.Sprintf("%s\x00%v", pointID(key, kpi.Date, kpi.Attributes), value)
+		if at, ok := d.seen[id]; (ok && now.Sub(at) < d.Window) || pushed[id] {
+			duplicates = append(duplicates, DataPoint{Key: key, Value: value, Date: kpi.Date, Unit: kpi.Unit, Attributes: kpi.Attributes})
+			return true
+		}
+		pushed[id] = true
+		return false
+	}
+
+	filtered := make([]KPI, 0, len(kpis))
+	for _, kpi := range kpis {
+		if kpi.Key != "" && duplicate(&kpi, kpi.Key, kpi.Value) {
+			kpi.Key, kpi.Value = "", 0
+		}
+		if len(kpi.Metrics) > 0 {
+			metrics := make(map[string]float32, len(kpi.Metrics))
+			for key, value := range kpi.Metrics {
+				if !duplicate(&kpi, key, value) {
+					metrics[key] = value
+				}
+			}
+			kpi.Metrics = metrics
+		}
+		if kpi.Key != "" || len(kpi.Metrics) > 0 {
+			filtered = append(filtered, kpi)
+		}
+	}
+
+	for _, point := range duplicates {
+		if d.OnDuplicate != nil {
+			if err := d.reportDuplicate(point); err != nil {
+				return nil, func() {}, err
+			}
+		}
+	}
+	if len(duplicates) > 0 && !d.Drop {
+		return nil, func() {}, fmt.Errorf("%w: metric %q date %q value %v", ErrDuplicate, duplicates[0].Key, duplicates[0].Date, duplicates[0].Value)
+	}
+
+	return filtered, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.seen == nil {
+			d.seen = make(map[string]time.Time)
+		}
+		for id := range pushed {
+			d.seen[id] = now
+		}
+	}, nil
+}
@@ -0,0 +1,59 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDedupCache(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var duplicates []DataPoint
+	client.DedupCache = &DedupCache{
+		Window:      time.Hour,
+		OnDuplicate: func(p DataPoint) { duplicates = append(duplicates, p) },
+		now:         func() time.Time { return now },
+	}
+	push := func(kpis ...KPI) error {
+		_, err := client.InsertAll(context.Background(), kpis, false)
+		return err
+	}
+
+	order := KPI{Key: "orders", Value: 1, Date: "2020-01-01 00:00:00"}
+	if err := push(order); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if err := push(order); !errors.Is(err, ErrDuplicate) {
+		t.Error("Expected ErrDuplicate", err)
+	}
+	if err := push(KPI{Key: "orders", Value: 2, Date: "2020-01-01 00:00:00"}); err != nil {
+		t.Error("Different value isn't a duplicate", err)
+	}
+
+	client.DedupCache.Drop = true
+	if err := push(order, KPI{Key: "orders", Value: 1, Date: "2020-01-01 00:01:00"}); err != nil {
+		t.Error("Duplicates must be dropped", err)
+	}
+	if err := push(order); err != nil || calls != 3 {
+		t.Error("Push of duplicates only must not be sent", err, calls)
+	}
+
+	now = now.Add(time.Hour)
+	if err := push(order); err != nil || calls != 4 {
+		t.Error("Data point must be forgotten after window", err, calls)
+	}
+	if len(duplicates) != 3 {
+		t.Error("Duplicates must be reported", duplicates)
+	}
+}
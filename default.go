@@ -0,0 +1,45 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrNoPushToken is returned by the package-level functions when the
+// DATABOX_PUSH_TOKEN environment variable is not set.
+var ErrNoPushToken = errors.New("DATABOX_PUSH_TOKEN is not set")
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     *Client
+)
+
+// DefaultClient returns the package-level Client used by Push and
+// InsertAll. It's created on the first call from the environment:
+// DATABOX_PUSH_TOKEN, and optionally DATABOX_PUSH_HOST. The Client can be
+// configured before use, like http.DefaultClient.
+func DefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewClient(os.Getenv("DATABOX_PUSH_TOKEN"))
+		if host := os.Getenv("DATABOX_PUSH_HOST"); host != "" {
+			defaultClient.PushHost = host
+		}
+	})
+	return defaultClient
+}
+
+// Push pushes kpi with DefaultClient.
+func Push(ctx context.Context, kpi *KPI) (*ResponseStatus, error) {
+	return InsertAll(ctx, []KPI{*kpi}, false)
+}
+
+// InsertAll pushes kpis with DefaultClient.
+func InsertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
+	client := DefaultClient()
+	if client.config().PushToken == "" {
+		return nil, ErrNoPushToken
+	}
+	return client.InsertAll(ctx, kpis, forcePush)
+}
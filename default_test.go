@@ -0,0 +1,39 @@
+package databox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// resetDefaultClient makes the next DefaultClient call create a new Client,
+// and again after the test.
+func resetDefaultClient(t *testing.T) {
+	reset := func() {
+		defaultClientOnce = sync.Once{}
+		defaultClient = nil
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestDefaultClient(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, _ = r.BasicAuth()
+		_, _ = w.Write([]byte(`{"id":"someRandomId"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("DATABOX_PUSH_TOKEN", "env-token")
+	t.Setenv("DATABOX_PUSH_HOST", server.URL)
+	resetDefaultClient(t)
+	if _, err := Push(context.Background(), &KPI{Key: "a", Value: 1}); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if token != "env-token" || DefaultClient() != DefaultClient() {
+		t.Error("Default client must be configured from environment", token)
+	}
+}
@@ -0,0 +1,86 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// diffHistory is the number of pushes fetched for Client.ReviewDiff.
+const diffHistory = findPushFirstPage
+
+// Delta compares a value to the last pushed value of its metric with the
+// same attributes, regardless of the date.
+type Delta struct {
+	Key        string
+	Date       string
+	Attributes map[string]interface{}
+	Value      float32
+	// Previous is the last pushed value, if HasPrevious is set.
+	Previous    float64
+	HasPrevious bool
+	// PreviousDate is the date of the previous value.
+	PreviousDate string
+}
+
+// Change returns the difference of Value and Previous.
+func (d Delta) Change() float64 {
+	return exactFloat64(d.Value) - d.Previous
+}
+
+func (d Delta) String() string {
+	if !d.HasPrevious {
+		return fmt.Sprintf("%s %s: %v (new)", d.Key, d.Date, d.Value)
+	}
+	return fmt.Sprintf("%s %s: %v -> %v (%+g)", d.Key, d.PreviousDate, d.Previous, d.Value, d.Change())
+}
+
+// Diff compares values of kpis to the last values of their metrics found in
+// the last history pushes, without pushing anything. It's a sanity check of
+// numbers before they are committed, e.g. in financial reporting. Pushes
+// rejected with errors are skipped.
+func (c *Client) Diff(ctx context.Context, kpis []KPI, history int) ([]Delta, error) {
+	pushes, err := c.LastPushesCtx(ctx, history)
+	if err != nil {
+		return nil, fmt.Errorf("fetching push history: %w", err)
+	}
+
+	// The history is ordered from the latest push, the first value wins.
+	last := make(map[string]historyPoint)
+	for _, point := range historyPoints(acceptedPushes(pushes)) {
+		id := pointID(point.key, "", point.attributes)
+		if _, ok := last[id]; !ok {
+			last[id] = point
+		}
+	}
+
+	var deltas []Delta
+	for _, kpi := range kpis {
+		values := kpiValues(kpi)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			delta := Delta{Key: key, Date: kpi.Date, Attributes: kpi.Attributes, Value: values[key]}
+			if point, ok := last[pointID(key, "", kpi.Attributes)]; ok {
+				delta.Previous = point.value
+				delta.HasPrevious = true
+				delta.PreviousDate = point.date
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas, nil
+}
+
+// reviewDiff passes the deltas of kpis to c.ReviewDiff.
+func (c *Client) reviewDiff(ctx context.Context, kpis []KPI) (err error) {
+	defer recoverPanic("ReviewDiff", &err)
+	deltas, err := c.Diff(ctx, kpis, diffHistory)
+	if err != nil {
+		return err
+	}
+	return c.ReviewDiff(ctx, deltas)
+}
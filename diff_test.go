@@ -0,0 +1,66 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestReviewDiff(t *testing.T) {
+	t.Parallel()
+
+	var posts int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return jsonResponse(200, `[
+				{"request":{"body":{"data":[{"$revenue":1e6,"date":"2020-01-03","region":"eu"},{"$revenue":1,"region":"us"}]},"errors":["invalid value"]}},
+				{"request":{"body":{"data":[{"$revenue":120,"date":"2020-01-02","region":"eu"}]}}},
+				{"request":{"body":{"data":[{"$revenue":100,"date":"2020-01-01","region":"eu"}]}}}
+			]`), nil
+		}
+		posts++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	errTooBig := errors.New("too big change")
+	var deltas []Delta
+	client.ReviewDiff = func(ctx context.Context, d []Delta) error {
+		deltas = d
+		for _, delta := range d {
+			if delta.HasPrevious && delta.Change() > 100 {
+				return errTooBig
+			}
+		}
+		return nil
+	}
+
+	eu := map[string]interface{}{"region": "eu"}
+	_, err := client.InsertAll(context.Background(), []KPI{
+		{Key: "revenue", Value: 130.5, Date: "2020-01-03", Attributes: eu},
+		{Key: "revenue", Value: 60, Attributes: map[string]interface{}{"region": "us"}},
+	}, false)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if posts != 1 || len(deltas) != 2 {
+		t.Fatal("Unexpected push", posts, deltas)
+	}
+	if d := deltas[0]; !d.HasPrevious || d.Previous != 120 || d.PreviousDate != "2020-01-02" || d.Change() != 10.5 {
+		t.Error("Last value of the metric must be compared", d)
+	}
+	if deltas[1].HasPrevious || deltas[1].String() != "revenue : 60 (new)" {
+		t.Error("Unexpected delta", deltas[1])
+	}
+	if deltas[0].String() != "revenue 2020-01-02: 120 -> 130.5 (+10.5)" {
+		t.Error("Unexpected delta", deltas[0])
+	}
+
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "revenue", Value: 500, Attributes: eu}}, false); !errors.Is(err, errTooBig) {
+		t.Error("Review must abort the push", err)
+	}
+	if posts != 1 {
+		t.Error("Nothing must be pushed", posts)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build !js && !tinygo
+
+package databox
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache caches host lookups of the Client's dialer, so short-lived
+// processes pushing in bursts don't resolve push.databox.com for every new
+// connection. The zero value caches for DefaultDNSTTL with
+// net.DefaultResolver and is safe for concurrent use.
+type DNSCache struct {
+	// TTL is how long resolved addresses are reused. Zero means DefaultDNSTTL.
+	TTL time.Duration
+	// Resolver resolves the hosts. Nil means net.DefaultResolver.
+	Resolver *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+	now     func() time.Time
+}
+
+// DefaultDNSTTL is the lifetime of DNSCache entries when TTL is not set.
+const DefaultDNSTTL = 5 * time.Minute
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// LookupHost returns the addresses of host, from cache when possible.
+func (d *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	now := time.Now
+	if d.now != nil {
+		now = d.now
+	}
+
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	d.mu.Unlock()
+	if ok && now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := d.TTL
+	if ttl == 0 {
+		ttl = DefaultDNSTTL
+	}
+	d.mu.Lock()
+	if d.entries == nil {
+		d.entries = make(map[string]dnsEntry)
+	}
+	d.entries[host] = dnsEntry{addrs: addrs, expires: now().Add(ttl)}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+// dialContext resolves the address with the cache and dials the resolved
+// addresses in order until one succeeds.
+func (d *DNSCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs, err := d.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !js && !tinygo
+
+package databox
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDNSCache(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	now := time.Now()
+	cache := &DNSCache{TTL: time.Minute, now: func() time.Time { return now }}
+	cache.entries = map[string]dnsEntry{
+		"databox.invalid": {addrs: []string{"127.0.0.1"}, expires: now.Add(time.Minute)},
+	}
+
+	client := NewClient(getToken(), WithDNSCache(cache))
+	// Every request must dial, a reused connection would skip the lookup.
+	client.transport().DisableKeepAlives = true
+	client.PushHost = "http://databox.invalid:" + port
+	response, err := client.Do(t.Context(), "GET", "/", nil)
+	if err != nil {
+		t.Fatal("Cached address must be dialed", err)
+	}
+	response.Body.Close()
+
+	now = now.Add(2 * time.Minute)
+	if _, err := client.Do(t.Context(), "GET", "/", nil); err == nil {
+		t.Error("Expired entry must be resolved again")
+	}
+}
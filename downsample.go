@@ -0,0 +1,100 @@
+package databox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Downsampler suppresses metrics whose value hasn't changed since their
+// previous push within Window. It cuts the requests for slow-moving gauges
+// like "licenses in use". Metrics with different unit or attributes are
+// tracked separately. Only KPIs without Date are downsampled, dated KPIs like
+// backfilled series are always pushed. Downsampler is safe for concurrent
+// use.
+type Downsampler struct {
+	// Window is the longest time an unchanged value is suppressed. After
+	// the window passes, the value is pushed again.
+	Window time.Duration
+
+	mu   sync.Mutex
+	last map[string]sample
+	now  func() time.Time
+}
+
+type sample struct {
+	value float32
+	at    time.Time
+}
+
+// filter returns kpis without unchanged metrics. The returned commit function
+// records the values as pushed; it's meant to be called after a successful
+// push.
+func (d *Downsampler) filter(kpis []KPI) ([]KPI, func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.now != nil {
+		now = d.now()
+	}
+	unchanged := func(id string, value float32) bool {
+		last, ok := d.last[id]
+		return ok && last.value == value && now.Sub(last.at) < d.Window
+	}
+
+	pushed := make(map[string]sample)
+	filtered := make([]KPI, 0, len(kpis))
+	for _, kpi := range kpis {
+		if kpi.Date != "" {
+			filtered = append(filtered, kpi)
+			continue
+		}
+		if kpi.Key != "" {
+			id := seriesID(kpi.Key, &kpi)
+			if unchanged(id, kpi.Value) {
+				kpi.Key, kpi.Value = "", 0
+			} else {
+				pushed[id] = sample{value: kpi.Value, at: now}
+			}
+		}
+		if len(kpi.Metrics) > 0 {
+			metrics := make(map[string]float32, len(kpi.Metrics))
+			for key, value := range kpi.Metrics {
+				id := seriesID(key, &kpi)
+				if !unchanged(id, value) {
+					metrics[key] = value
+					pushed[id] = sample{value: value, at: now}
+				}
+			}
+			kpi.Metrics = metrics
+		}
+		if kpi.Key != "" || len(kpi.Metrics) > 0 {
+			filtered = append(filtered, kpi)
+		}
+	}
+
+	return filtered, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.last == nil {
+			d.last = make(map[string]sample)
+		}
+		for id, s := range pushed {
+			d.last[id] = s
+		}
+	}
+}
+
+// seriesID identifies the time series of the metric key within kpi by the
+// key, unit and attributes.
+func seriesID(key string, kpi *KPI) string {
+	attributes := make([]string, 0, len(kpi.Attributes))
+	for name, value := range kpi.Attributes {
+		attributes = append(attributes, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(attributes)
+	return key + "\x00" + kpi.Unit + "\x00" + strings.Join(attributes, "\x00")
+}
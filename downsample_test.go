@@ -0,0 +1,62 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDownsampler(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	statusCode := 200
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(statusCode, `{"id":"someRandomId","type":"error","message":"fail"}`), nil
+	})
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.Downsampler = &Downsampler{Window: time.Hour, now: func() time.Time { return now }}
+	push := func(kpis ...KPI) error {
+		_, err := client.InsertAll(context.Background(), kpis, false)
+		return err
+	}
+
+	_ = push(KPI{Key: "licenses", Value: 10}, KPI{Metrics: map[string]float32{"seats": 5, "admins": 2}})
+	_ = push(KPI{Key: "licenses", Value: 10}, KPI{Metrics: map[string]float32{"seats": 6, "admins": 2}})
+	_ = push(KPI{Key: "licenses", Value: 10, Attributes: map[string]interface{}{"plan": "pro"}})
+	now = now.Add(time.Hour)
+	_ = push(KPI{Key: "licenses", Value: 10})
+
+	if len(requests) != 4 {
+		t.Fatal("Unexpected requests", requests)
+	}
+	if len(requests[1].Data) != 1 || len(requests[1].Data[0]) != 1 || requests[1].Data[0]["$seats"] == nil {
+		t.Error("Unchanged values must be suppressed", requests[1])
+	}
+
+	statusCode = 500
+	if err := push(KPI{Key: "users", Value: 1}); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	statusCode = 200
+	_ = push(KPI{Key: "users", Value: 1})
+	_ = push(KPI{Key: "users", Value: 1})
+	if len(requests) != 6 {
+		t.Error("Failed push must not be remembered", len(requests))
+	}
+
+	_ = push(KPI{Key: "users", Value: 1, Date: "2019-12-30"})
+	_ = push(KPI{Key: "users", Value: 1, Date: "2019-12-31"})
+	if len(requests) != 8 || len(requests[7].Data) != 1 {
+		t.Error("Dated KPIs must not be downsampled", requests[6:])
+	}
+}
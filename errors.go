@@ -0,0 +1,116 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// APIError is returned when the API responds to a push with an error status.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Type is the error type reported by the API, e.g. "invalid_json".
+	Type string
+	// Message describes the error. If the response isn't a JSON error, it
+	// is the response body.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+	}
+	return e.Type + ": " + e.Message
+}
+
+// ErrorKind classifies errors returned by the client, so applications can
+// decide about retries and alerting without matching error messages.
+type ErrorKind int
+
+const (
+	// KindUnknown is an error that doesn't fall into any other kind.
+	KindUnknown ErrorKind = iota
+	// KindValidation is a request the API or the client refused as invalid.
+	// Retrying it fails the same way.
+	KindValidation
+	// KindAuth is a missing, invalid or unauthorized push token.
+	KindAuth
+	// KindRateLimit is a request throttled by the API.
+	KindRateLimit
+	// KindServer is a failure of the API.
+	KindServer
+	// KindNetwork is a failure to reach the API, including timeouts.
+	KindNetwork
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindValidation:
+		return "validation"
+	case KindAuth:
+		return "auth"
+	case KindRateLimit:
+		return "rate limit"
+	case KindServer:
+		return "server"
+	case KindNetwork:
+		return "network"
+	}
+	return "unknown"
+}
+
+// KindOf returns the kind of err. Wrapped errors are inspected.
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.StatusCode; {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return KindAuth
+		case code == http.StatusTooManyRequests:
+			return KindRateLimit
+		case code >= 500:
+			return KindServer
+		case code >= 400:
+			return KindValidation
+		}
+		return KindUnknown
+	}
+
+	var decodeErr *DecodeError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrNoPushToken):
+		return KindAuth
+	case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrSchemaViolation),
+		errors.Is(err, ErrAnomaly), errors.Is(err, ErrDuplicate):
+		return KindValidation
+	case errors.As(err, &decodeErr):
+		return KindServer
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return KindNetwork
+	}
+	return KindUnknown
+}
+
+// IsRetryable reports whether the operation failing with err may succeed
+// when retried: rate limited requests, server and network failures.
+// Cancelled contexts, refused redirects and pinning failures are permanent.
+func IsRetryable(err error) bool {
+	var redirectErr *RedirectError
+	var pinErr *PinError
+	if errors.Is(err, context.Canceled) || errors.As(err, &redirectErr) || errors.As(err, &pinErr) {
+		return false
+	}
+	switch KindOf(err) {
+	case KindRateLimit, KindServer, KindNetwork:
+		return true
+	}
+	return false
+}
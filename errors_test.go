@@ -0,0 +1,57 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	t.Parallel()
+
+	push := func(status int, body string) error {
+		client := NewClient(getToken())
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(status, body), nil
+		})
+		_, err := client.InsertAll(context.Background(), []KPI{{Key: "a", Value: 1}}, false)
+		return err
+	}
+	networkErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name      string
+		err       error
+		kind      ErrorKind
+		retryable bool
+	}{
+		{"validation", push(400, `{"type":"invalid_json","message":"some error message"}`), KindValidation, false},
+		{"auth", push(401, `{"type":"unauthorized","message":"invalid token"}`), KindAuth, false},
+		{"rate limit", push(429, `{"type":"too_many_requests","message":"slow down"}`), KindRateLimit, true},
+		{"server", push(502, `<html>Bad Gateway</html>`), KindServer, true},
+		{"network", fmt.Errorf("pushing: %w", networkErr), KindNetwork, true},
+		{"deadline", context.DeadlineExceeded, KindNetwork, true},
+		{"canceled", context.Canceled, KindUnknown, false},
+		{"invalid key", fmt.Errorf("checking: %w", ErrInvalidKey), KindValidation, false},
+		{"no token", ErrNoPushToken, KindAuth, false},
+		{"redirect", &RedirectError{Method: "POST", StatusCode: 307}, KindUnknown, false},
+		{"pinning", &net.OpError{Op: "remote error", Err: &PinError{Host: "push.databox.com"}}, KindNetwork, false},
+		{"nil", nil, KindUnknown, false},
+	}
+	for _, test := range tests {
+		if kind := KindOf(test.err); kind != test.kind {
+			t.Errorf("%s: expected kind %v, got %v (%v)", test.name, test.kind, kind, test.err)
+		}
+		if retryable := IsRetryable(test.err); retryable != test.retryable {
+			t.Errorf("%s: expected retryable %v, got %v", test.name, test.retryable, retryable)
+		}
+	}
+
+	var apiErr *APIError
+	if err := push(502, `<html>Bad Gateway</html>`); !errors.As(err, &apiErr) || apiErr.StatusCode != 502 || apiErr.Message != "<html>Bad Gateway</html>" {
+		t.Error("Unexpected error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package databox
+
+import (
+	"fmt"
+	"time"
+)
+
+// FiscalCalendar maps dates to fiscal periods, e.g. for finance dashboards
+// asking for fiscal quarters. The zero value is the calendar year in UTC.
+type FiscalCalendar struct {
+	// StartMonth is the first month of the fiscal year. Zero means January.
+	StartMonth time.Month
+	// Location is the time zone of the calendar. Nil means UTC.
+	Location *time.Location
+}
+
+func (c FiscalCalendar) start() time.Month {
+	if c.StartMonth < time.January || c.StartMonth > time.December {
+		return time.January
+	}
+	return c.StartMonth
+}
+
+// monthsInto returns the number of months of the fiscal year before the
+// month of t.
+func (c FiscalCalendar) monthsInto(t time.Time) int {
+	return (int(t.Month()) - int(c.start()) + 12) % 12
+}
+
+func (c FiscalCalendar) in(t time.Time) time.Time {
+	if c.Location == nil {
+		return t.UTC()
+	}
+	return t.In(c.Location)
+}
+
+// Year returns the fiscal year of t, named by the calendar year it ends in;
+// with StartMonth July, July 2020 belongs to fiscal year 2021.
+func (c FiscalCalendar) Year(t time.Time) int {
+	t = c.in(t)
+	if c.start() != time.January && t.Month() >= c.start() {
+		return t.Year() + 1
+	}
+	return t.Year()
+}
+
+// Quarter returns the fiscal quarter of t, 1 to 4.
+func (c FiscalCalendar) Quarter(t time.Time) int {
+	return c.monthsInto(c.in(t))/3 + 1
+}
+
+// Truncate returns the start of the bucket containing t. Quarters and years
+// are the fiscal ones, the other buckets are the same as Bucket.Truncate.
+func (c FiscalCalendar) Truncate(t time.Time, bucket Bucket) time.Time {
+	t = c.in(t)
+	var months int
+	switch bucket {
+	case BucketQuarter:
+		months = c.monthsInto(t) % 3
+	case BucketYear:
+		months = c.monthsInto(t)
+	default:
+		return bucket.Truncate(t, t.Location())
+	}
+	return time.Date(t.Year(), t.Month()-time.Month(months), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Date returns the start of the bucket containing t formatted as KPI date in
+// DateTimeTZFormat, see Truncate.
+func (c FiscalCalendar) Date(t time.Time, bucket Bucket) string {
+	return c.Truncate(t, bucket).Format(DateTimeTZFormat)
+}
+
+// Attributes returns the fiscal_year and fiscal_quarter attributes of t, e.g.
+// "FY2021" and "FY2021-Q1".
+func (c FiscalCalendar) Attributes(t time.Time) map[string]interface{} {
+	year := fmt.Sprintf("FY%d", c.Year(t))
+	return map[string]interface{}{
+		"fiscal_year":    year,
+		"fiscal_quarter": fmt.Sprintf("%s-Q%d", year, c.Quarter(t)),
+	}
+}
+
+// Transform returns a Transform adding the fiscal attributes of the KPI
+// dates, see Attributes. Dates without a zone are in the calendar's Location.
+// KPIs without a date are dated now; attributes set on the KPIs are kept.
+func (c FiscalCalendar) Transform() Transform {
+	return TransformEach(func(kpi KPI) ([]KPI, error) {
+		date := time.Now()
+		if kpi.Date != "" {
+			var err error
+			if date, err = parseDateIn(kpi.Date, c.Location); err != nil {
+				return nil, err
+			}
+		}
+		return withAttributes([]KPI{kpi}, c.Attributes(date), false), nil
+	})
+}
@@ -0,0 +1,62 @@
+package databox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFiscalCalendar(t *testing.T) {
+	t.Parallel()
+
+	calendar := FiscalCalendar{StartMonth: time.July}
+	tests := []struct {
+		date    time.Time
+		year    int
+		quarter int
+		start   string
+	}{
+		{time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC), 2021, 1, "2020-07-01 00:00:00+00:00"},
+		{time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), 2021, 2, "2020-10-01 00:00:00+00:00"},
+		{time.Date(2021, 2, 15, 0, 0, 0, 0, time.UTC), 2021, 3, "2021-01-01 00:00:00+00:00"},
+		{time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC), 2021, 4, "2021-04-01 00:00:00+00:00"},
+	}
+	for _, test := range tests {
+		if year, quarter := calendar.Year(test.date), calendar.Quarter(test.date); year != test.year || quarter != test.quarter {
+			t.Errorf("%s: got FY%d Q%d", test.date, year, quarter)
+		}
+		if start := calendar.Date(test.date, BucketQuarter); start != test.start {
+			t.Errorf("%s: quarter starts %s, want %s", test.date, start, test.start)
+		}
+		if start := calendar.Date(test.date, BucketYear); start != "2020-07-01 00:00:00+00:00" {
+			t.Errorf("%s: year starts %s", test.date, start)
+		}
+	}
+	if year := (FiscalCalendar{}).Year(tests[0].date); year != 2020 {
+		t.Error("Zero calendar must be the calendar year", year)
+	}
+
+	kpis, err := calendar.Transform()(context.Background(), []KPI{
+		{Key: "revenue", Date: "2020-08-15"},
+		{Key: "revenue", Date: "2021-01-01 10:00:00", Attributes: map[string]interface{}{"fiscal_quarter": "custom"}},
+	})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if kpis[0].Attributes["fiscal_year"] != "FY2021" || kpis[0].Attributes["fiscal_quarter"] != "FY2021-Q1" || kpis[1].Attributes["fiscal_quarter"] != "custom" {
+		t.Error("Unexpected attributes", kpis[0].Attributes, kpis[1].Attributes)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar.Location = newYork
+	kpis, err = calendar.Transform()(context.Background(), []KPI{{Key: "revenue", Date: "2021-07-01"}})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if kpis[0].Attributes["fiscal_quarter"] != "FY2022-Q1" {
+		t.Error("Dates without a zone must be in the calendar's location", kpis[0].Attributes)
+	}
+}
@@ -0,0 +1,60 @@
+package databox
+
+import "sort"
+
+// KPIOption sets a field shared by the KPIs built by FromMap.
+type KPIOption func(*KPI)
+
+// WithDate sets the date of the KPIs, formatted as in KPI.Date.
+func WithDate(date string) KPIOption {
+	return func(kpi *KPI) {
+		kpi.Date = date
+	}
+}
+
+// WithUnit sets the unit of the KPIs.
+func WithUnit(unit string) KPIOption {
+	return func(kpi *KPI) {
+		kpi.Unit = unit
+	}
+}
+
+// WithKPIAttributes sets the attributes of the KPIs. Repeated use merges the
+// attributes, later values win. The map is copied.
+func WithKPIAttributes(attributes map[string]interface{}) KPIOption {
+	return func(kpi *KPI) {
+		merged := make(map[string]interface{}, len(kpi.Attributes)+len(attributes))
+		for name, value := range kpi.Attributes {
+			merged[name] = value
+		}
+		for name, value := range attributes {
+			merged[name] = value
+		}
+		kpi.Attributes = merged
+	}
+}
+
+// FromMap converts metric values keyed by metric key into KPIs sorted by key,
+// applying opts to each of them. It is the counterpart of FromStruct for data
+// whose metrics are known only at runtime.
+func FromMap(values map[string]float64, opts ...KPIOption) []KPI {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var template KPI
+	for _, opt := range opts {
+		opt(&template)
+	}
+
+	kpis := make([]KPI, len(keys))
+	for i, key := range keys {
+		kpi := template
+		kpi.Key = key
+		kpi.Value = float32(values[key])
+		kpis[i] = kpi
+	}
+	return kpis
+}
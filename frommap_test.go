@@ -0,0 +1,30 @@
+package databox
+
+import "testing"
+
+func TestFromMap(t *testing.T) {
+	t.Parallel()
+
+	kpis := FromMap(
+		map[string]float64{"visits": 10, "orders": 2.5},
+		WithDate("2020-01-01"),
+		WithUnit("count"),
+		WithKPIAttributes(map[string]interface{}{"region": "eu", "channel": "web"}),
+		WithKPIAttributes(map[string]interface{}{"channel": "app"}),
+	)
+	if len(kpis) != 2 {
+		t.Fatal("Unexpected KPIs", kpis)
+	}
+	if kpis[0].Key != "orders" || kpis[0].Value != 2.5 || kpis[1].Key != "visits" || kpis[1].Value != 10 {
+		t.Error("KPIs must be sorted by key", kpis)
+	}
+	for _, kpi := range kpis {
+		if kpi.Date != "2020-01-01" || kpi.Unit != "count" || kpi.Attributes["region"] != "eu" || kpi.Attributes["channel"] != "app" {
+			t.Error("Options not applied", kpi)
+		}
+	}
+
+	if kpis := FromMap(nil); len(kpis) != 0 {
+		t.Error("Must be empty", kpis)
+	}
+}
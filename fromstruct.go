@@ -0,0 +1,138 @@
+package databox
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FromStruct converts a struct, a pointer to it, or a slice of them into
+// KPIs according to the struct tags:
+//
+//	type Sales struct {
+//		Revenue float64   `databox:"key=sales,unit=USD"`
+//		Orders  int       `databox:"key=orders"`
+//		Region  string    `databoxattr:"region"`
+//		Day     time.Time `databox:"date"`
+//	}
+//
+// Every numeric field tagged with databox becomes a KPI; the key defaults to
+// the field name. Fields tagged with databoxattr become attributes of all
+// KPIs of the struct. The databox:"date" field, a time.Time formatted in
+// DateTimeTZFormat or a string, dates all KPIs of the struct. Nil pointer
+// fields are skipped.
+func FromStruct(v interface{}) ([]KPI, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return structKPIs(value)
+	case reflect.Slice, reflect.Array:
+		var kpis []KPI
+		for i := 0; i < value.Len(); i++ {
+			item, err := FromStruct(value.Index(i).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			kpis = append(kpis, item...)
+		}
+		return kpis, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s, expected a struct", value.Type())
+}
+
+func structKPIs(value reflect.Value) ([]KPI, error) {
+	var kpis []KPI
+	var date string
+	attributes := make(map[string]interface{})
+
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		for fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				break
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		if fieldValue.Kind() == reflect.Ptr {
+			continue
+		}
+
+		if name, ok := field.Tag.Lookup("databoxattr"); ok {
+			if name == "" {
+				name = field.Name
+			}
+			attributes[name] = fieldValue.Interface()
+		}
+		tag, ok := field.Tag.Lookup("databox")
+		if !ok {
+			continue
+		}
+		options := parseStructTag(tag)
+		if _, ok := options["date"]; ok {
+			switch {
+			case fieldValue.Type() == timeType:
+				date = fieldValue.Interface().(time.Time).Format(DateTimeTZFormat)
+			case fieldValue.Kind() == reflect.String:
+				date = fieldValue.String()
+			default:
+				return nil, fmt.Errorf("date field %s must be time.Time or string", field.Name)
+			}
+			continue
+		}
+
+		kpi := KPI{Key: options["key"], Unit: options["unit"]}
+		if kpi.Key == "" {
+			kpi.Key = field.Name
+		}
+		switch fieldValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			kpi.Value = float32(fieldValue.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			kpi.Value = float32(fieldValue.Uint())
+		case reflect.Float32, reflect.Float64:
+			kpi.Value = float32(fieldValue.Float())
+		default:
+			return nil, fmt.Errorf("field %s of type %s is not numeric", field.Name, fieldValue.Type())
+		}
+		kpis = append(kpis, kpi)
+	}
+
+	for i := range kpis {
+		kpis[i].Date = date
+		if len(attributes) > 0 {
+			kpis[i].Attributes = attributes
+		}
+	}
+	return kpis, nil
+}
+
+// parseStructTag parses comma-separated options of the databox tag, like
+// "key=sales,unit=USD". Options without a value map to "".
+func parseStructTag(tag string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range strings.Split(tag, ",") {
+		name, value := option, ""
+		if i := strings.IndexByte(option, '='); i >= 0 {
+			name, value = option[:i], option[i+1:]
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			options[name] = strings.TrimSpace(value)
+		}
+	}
+	return options
+}
@@ -0,0 +1,54 @@
+package databox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromStruct(t *testing.T) {
+	t.Parallel()
+
+	type sales struct {
+		Revenue  float64   `databox:"key=sales,unit=USD"`
+		Orders   int       `databox:"key=orders"`
+		Refunds  *uint     `databox:""`
+		Region   string    `databoxattr:"region"`
+		Channel  *string   `databoxattr:""`
+		Day      time.Time `databox:"date"`
+		Internal string
+		private  int
+	}
+	web := "web"
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	kpis, err := FromStruct([]*sales{
+		{Revenue: 99.5, Orders: 3, Region: "eu", Channel: &web, Day: day},
+		nil,
+	})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(kpis) != 2 {
+		t.Fatal("Unexpected KPIs", kpis)
+	}
+	if k := kpis[0]; k.Key != "sales" || k.Value != 99.5 || k.Unit != "USD" || k.Date != "2020-01-01 00:00:00+00:00" || k.Attributes["region"] != "eu" || k.Attributes["Channel"] != "web" {
+		t.Error("Unexpected KPI", k)
+	}
+	if k := kpis[1]; k.Key != "orders" || k.Value != 3 || k.Unit != "" {
+		t.Error("Unexpected KPI", k)
+	}
+
+	refunds := uint(1)
+	kpis, _ = FromStruct(sales{Refunds: &refunds})
+	if len(kpis) != 3 || kpis[2].Key != "Refunds" || kpis[2].Value != 1 {
+		t.Error("Key must default to field name", kpis)
+	}
+
+	if _, err := FromStruct(struct {
+		Name string `databox:"key=name"`
+	}{}); err == nil {
+		t.Error("Non-numeric field must fail")
+	}
+	if _, err := FromStruct(42); err == nil {
+		t.Error("Non-struct must fail")
+	}
+}
@@ -0,0 +1,102 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzKPIAppendJSON(f *testing.F) {
+	f.Add("sales", float32(1.5), "region", "eu", "2020-01-01")
+	f.Add("quote\"key", float32(-0.1), "$quote\"key", " ", "")
+	f.Add("", float32(0), "date", "\x00\xff", "")
+	f.Add("tab\tkey", float32(1e21), "<html>", "&", "2020-01-01 00:00:00+00:00")
+
+	f.Fuzz(func(t *testing.T, key string, value float32, attribute, attributeValue, date string) {
+		kpi := KPI{
+			Key:        key,
+			Value:      value,
+			Metrics:    map[string]float32{"other": value},
+			Date:       date,
+			Attributes: map[string]interface{}{attribute: attributeValue},
+		}
+		got := kpi.AppendJSON(nil)
+		if !json.Valid(got) {
+			t.Fatalf("Invalid JSON %q", got)
+		}
+		if !isFinite(value) || !utf8.ValidString(key+attribute) {
+			// encoding/json can't encode the value, and invalid UTF-8 may
+			// merge distinct names into one.
+			return
+		}
+		want, err := json.Marshal(kpi.ToJSONData())
+		if err != nil {
+			t.Fatal("Must be nil", err)
+		}
+		assertJSONEqual(t, got, want)
+	})
+}
+
+func FuzzSerialize(f *testing.F) {
+	f.Add(`[{"$sales":1.5,"date":"2020-01-01","unit":"USD","region":"eu"}]`)
+	f.Add(`[{"$a":1,"$b":2,"nested":{"x":[1,"y",null]}},{"$c":-0}]`)
+	f.Add(`[{"$ ":1e-7,"attr":"\ud800"}]`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var kpis []KPI
+		if err := json.Unmarshal([]byte(input), &kpis); err != nil {
+			return
+		}
+		for _, serializer := range []Serializer{AppendSerializer{}, JSONSerializer{}} {
+			payload, err := serializer.Serialize(kpis, map[string]interface{}{"ensure_unique": true})
+			if err != nil {
+				t.Fatal("Must be nil", err)
+			}
+			var wrap KPIWrap
+			if err := json.Unmarshal(payload, &wrap); err != nil {
+				t.Fatalf("Invalid payload %q: %v", payload, err)
+			}
+			if len(wrap.Data) != len(kpis) {
+				t.Fatalf("Expected %d items, got %q", len(kpis), payload)
+			}
+		}
+	})
+}
+
+func FuzzResponse(f *testing.F) {
+	f.Add(200, `{"id":"someRandomId"}`)
+	f.Add(400, `{"type":"invalid_json","message":"Invalid JSON"}`)
+	f.Add(200, `[{"request":{"date":"2020-01-01T00:00:00Z","body":{"data":[{"$a":1}]},"errors":["bad",{"index":0}]},"response":{"body":{"id":"x"}}}]`)
+	f.Add(500, `<html>`)
+	f.Add(200, `{"id":`)
+
+	f.Fuzz(func(t *testing.T, status int, body string) {
+		if status < 100 || status > 599 {
+			return
+		}
+		client := NewClient(getToken())
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		})
+
+		ctx := context.Background()
+		_, _ = client.InsertAll(ctx, []KPI{{Key: "a", Value: 1}}, false)
+		_, _ = client.LastPushesCtx(ctx, 10)
+		_, _, _ = client.LastValue(ctx, "a")
+
+		var pushErrors []PushError
+		if err := json.Unmarshal([]byte(body), &pushErrors); err == nil {
+			for _, pushErr := range pushErrors {
+				_ = pushErr.Error()
+			}
+		}
+	})
+}
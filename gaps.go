@@ -0,0 +1,50 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DetectGaps returns the expected timestamps of the metric key that have no
+// value in the push history of the last window. The timestamps are the
+// starts of interval buckets, aligned like time.Truncate, so 24h buckets
+// start at midnight UTC. Buckets starting before the window and the bucket in
+// progress are not expected. Values are matched by their date, or the time of
+// the push if they have none. Pushes rejected with errors don't fill a gap.
+func (c *Client) DetectGaps(ctx context.Context, key string, interval, window time.Duration) ([]time.Time, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid interval %s", interval)
+	}
+	pushes, err := c.historyWithin(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+
+	pushed := make(map[time.Time]bool)
+	for _, point := range historyPoints(acceptedPushes(pushes)) {
+		if point.key != key {
+			continue
+		}
+		date, err := parseDate(point.date)
+		if err != nil {
+			if date, err = time.Parse(time.RFC3339, point.pushDate); err != nil {
+				continue
+			}
+		}
+		pushed[date.Truncate(interval).UTC()] = true
+	}
+
+	now := time.Now()
+	start := now.Add(-window).Truncate(interval)
+	if start.Before(now.Add(-window)) {
+		start = start.Add(interval)
+	}
+	var gaps []time.Time
+	for bucket := start; !bucket.Add(interval).After(now); bucket = bucket.Add(interval) {
+		if !pushed[bucket.UTC()] {
+			gaps = append(gaps, bucket.UTC())
+		}
+	}
+	return gaps, nil
+}
@@ -1,3 +1,3 @@
 module github.com/databox/databox-go
 
-go 1.15
+go 1.24
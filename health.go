@@ -0,0 +1,65 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrUnhealthy is wrapped by errors of HealthCheck.Check.
+var ErrUnhealthy = errors.New("databox client is unhealthy")
+
+// HealthCheck reports health of a Client, e.g. to readiness probes of metric
+// forwarders. It's an http.Handler responding 200 when healthy and 503 with
+// the reason otherwise.
+type HealthCheck struct {
+	Client *Client
+	// CheckToken verifies the push token by fetching the last push. It
+	// costs an API request per check.
+	CheckToken bool
+	// MaxPushAge, if set, fails the check when there was no successful push
+	// for longer. It requires Client.Stats; the age is counted from the
+	// creation of the HealthCheck until the first push.
+	MaxPushAge time.Duration
+
+	created time.Time
+}
+
+// NewHealthCheck returns a HealthCheck of c.
+func NewHealthCheck(c *Client) *HealthCheck {
+	return &HealthCheck{Client: c, created: time.Now()}
+}
+
+// Check returns nil if the Client is healthy, an error wrapping ErrUnhealthy
+// otherwise.
+func (h *HealthCheck) Check(ctx context.Context) error {
+	if h.MaxPushAge > 0 {
+		if h.Client.Stats == nil {
+			return fmt.Errorf("%w: push age unknown, Client.Stats is not set", ErrUnhealthy)
+		}
+		last := h.Client.Stats.Snapshot().LastPush
+		if last.IsZero() {
+			last = h.created
+		}
+		if age := time.Since(last); age > h.MaxPushAge {
+			return fmt.Errorf("%w: last push %s ago", ErrUnhealthy, age.Round(time.Second))
+		}
+	}
+	if h.CheckToken {
+		if _, err := h.Client.LastPushesCtx(ctx, 1); err != nil {
+			return fmt.Errorf("%w: checking token: %v", ErrUnhealthy, err)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HealthCheck) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.Check(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,49 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	statusCode := 401
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if statusCode == 401 {
+			return jsonResponse(statusCode, `{"type":"invalid_token","message":"Unauthorized"}`), nil
+		}
+		return jsonResponse(statusCode, `[]`), nil
+	})
+	client.Stats = &Stats{}
+
+	check := NewHealthCheck(client)
+	check.CheckToken = true
+	if err := check.Check(context.Background()); !errors.Is(err, ErrUnhealthy) {
+		t.Error("Invalid token must be unhealthy", err)
+	}
+	statusCode = 200
+	if err := check.Check(context.Background()); err != nil {
+		t.Error("Must be healthy", err)
+	}
+
+	check.MaxPushAge = time.Minute
+	check.created = time.Now().Add(-time.Hour)
+	recorder := httptest.NewRecorder()
+	check.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Error("Missing pushes must be unhealthy", recorder.Code, recorder.Body)
+	}
+
+	client.Stats.recordPush([]KPI{{Key: "a"}}, nil)
+	recorder = httptest.NewRecorder()
+	check.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Error("Must be healthy", recorder.Code, recorder.Body)
+	}
+}
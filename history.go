@@ -0,0 +1,162 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyPoint is a single metric value found in the push history.
+type historyPoint struct {
+	key        string
+	value      float64
+	date       string
+	unit       string
+	attributes map[string]interface{}
+	// pushID is the response ID of the push carrying the point.
+	pushID string
+	// pushDate is the time the push was received.
+	pushDate string
+}
+
+// historyPoints extracts metric values from the pushes, in the order of the
+// pushes.
+func historyPoints(pushes []LastPush) []historyPoint {
+	var points []historyPoint
+	for _, push := range pushes {
+		for _, item := range push.Request.Body.Data {
+			date, _ := item["date"].(string)
+			unit, _ := item["unit"].(string)
+			attributes := make(map[string]interface{})
+			for name, value := range item {
+				if !strings.HasPrefix(name, "$") && name != "date" && name != "unit" {
+					attributes[name] = value
+				}
+			}
+			for name, value := range item {
+				number, ok := value.(float64)
+				if !strings.HasPrefix(name, "$") || !ok {
+					continue
+				}
+				points = append(points, historyPoint{
+					key:        strings.TrimPrefix(name, "$"),
+					value:      number,
+					date:       date,
+					unit:       unit,
+					attributes: attributes,
+					pushID:     push.Response.Body.ID,
+					pushDate:   push.Request.Date,
+				})
+			}
+		}
+	}
+	return points
+}
+
+// pointID identifies a data point by metric key, date and attributes.
+// Attribute values are compared by their text form, so 10 and 10.0 are equal.
+func pointID(key, date string, attributes map[string]interface{}) string {
+	pairs := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(pairs)
+	return key + "\x00" + date + "\x00" + strings.Join(pairs, "\x00")
+}
+
+// historyWithin returns pushes received within window before now. The
+// history is requested in pages of growing size, like FindPush does, until a
+// push older than window is reached or there are no older pushes.
+func (c *Client) historyWithin(ctx context.Context, window time.Duration) ([]LastPush, error) {
+	since := time.Now().Add(-window)
+	for limit := findPushFirstPage; ; limit *= 2 {
+		pushes, err := c.LastPushesCtx(ctx, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i, push := range pushes {
+			if date, err := time.Parse(time.RFC3339, push.Request.Date); err == nil && date.Before(since) {
+				return pushes[:i], nil
+			}
+		}
+		if len(pushes) < limit || limit >= findPushMaxDepth {
+			return pushes, nil
+		}
+	}
+}
+
+// ErrMetricNotFound is returned by LastValue when the metric is not present
+// in the recent history.
+var ErrMetricNotFound = errors.New("metric not found")
+
+// LastValue returns the latest value of the metric key and its date, or the
+// time of the push if the value has no date. Of the values found in the
+// recent history, the one with the latest date wins, and the most recent push
+// breaks ties. Pushes rejected with errors are skipped. The history is
+// requested in pages of growing size, like FindPush does.
+func (c *Client) LastValue(ctx context.Context, key string) (float64, time.Time, error) {
+	for limit := findPushFirstPage; limit <= findPushMaxDepth; limit *= 2 {
+		pushes, err := c.LastPushesCtx(ctx, limit)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		accepted := acceptedPushes(pushes)
+		var value float64
+		var last time.Time
+		found := false
+		for _, point := range historyPoints(accepted) {
+			if point.key != key {
+				continue
+			}
+			date, err := parseDate(point.date)
+			if err != nil {
+				date, _ = time.Parse(time.RFC3339, point.pushDate)
+			}
+			if !found || date.After(last) {
+				value, last, found = point.value, date, true
+			}
+		}
+		if found {
+			return value, last, nil
+		}
+		if len(pushes) < limit {
+			break
+		}
+	}
+	return 0, time.Time{}, fmt.Errorf("looking up metric %q: %w", key, ErrMetricNotFound)
+}
+
+// acceptedPushes returns pushes without those rejected with errors, whose
+// data didn't land.
+func acceptedPushes(pushes []LastPush) []LastPush {
+	accepted := make([]LastPush, 0, len(pushes))
+	for _, push := range pushes {
+		if len(push.Request.Errors) == 0 {
+			accepted = append(accepted, push)
+		}
+	}
+	return accepted
+}
+
+// parseDate parses KPI date in any of the formats accepted by the API.
+// Dates without a zone are in UTC.
+func parseDate(date string) (time.Time, error) {
+	return parseDateIn(date, time.UTC)
+}
+
+// parseDateIn is like parseDate, but dates without a zone are in loc. Nil
+// means UTC.
+func parseDateIn(date string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range []string{DateTimeTZFormat, DateTimeFormat, DateFormat, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q", date)
+}
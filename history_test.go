@@ -0,0 +1,69 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLastValue(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `[
+			{"request":{"date":"2020-01-04T10:00:00Z","body":{"data":[{"$revenue":1e9,"date":"2020-01-04"}]},"errors":["invalid value"]}},
+			{"request":{"date":"2020-01-03T12:00:00Z","body":{"data":[{"$revenue":90,"date":"2019-12-31"}]}}},
+			{"request":{"date":"2020-01-03T10:00:00Z","body":{"data":[{"$orders":7}]}}},
+			{"request":{"date":"2020-01-02T10:00:00Z","body":{"data":[{"$revenue":120.5,"date":"2020-01-02 08:00:00"}]}}},
+			{"request":{"date":"2020-01-01T10:00:00Z","body":{"data":[{"$revenue":100,"date":"2020-01-01"}]}}}
+		]`), nil
+	})
+
+	value, date, err := client.LastValue(context.Background(), "revenue")
+	if err != nil || value != 120.5 || !date.Equal(time.Date(2020, 1, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Error("Unexpected last value", value, date, err)
+	}
+	value, date, err = client.LastValue(context.Background(), "orders")
+	if err != nil || value != 7 || !date.Equal(time.Date(2020, 1, 3, 10, 0, 0, 0, time.UTC)) {
+		t.Error("Push date must be used for value without date", value, date, err)
+	}
+	if _, _, err := client.LastValue(context.Background(), "visitors"); !errors.Is(err, ErrMetricNotFound) {
+		t.Error("Expected ErrMetricNotFound", err)
+	}
+}
+
+func TestDetectGaps(t *testing.T) {
+	t.Parallel()
+
+	day := 24 * time.Hour
+	today := time.Now().UTC().Truncate(day)
+	date := func(days int) string { return today.AddDate(0, 0, -days).Format(DateFormat) }
+	pushDate := time.Now().UTC().Format(time.RFC3339)
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `[
+			{"request":{"date":"`+pushDate+`","body":{"data":[{"$revenue":1,"date":"`+date(3)+`"}]},"errors":["invalid value"]}},
+			{"request":{"date":"`+pushDate+`","body":{"data":[{"$revenue":1,"date":"`+date(1)+`"},{"$revenue":1,"date":"`+date(2)+`"},{"$orders":1,"date":"`+date(3)+`"}]}}},
+			{"request":{"date":"`+pushDate+`","body":{"data":[{"$revenue":1,"date":"`+date(4)+`"}]}}}
+		]`), nil
+	})
+
+	gaps, err := client.DetectGaps(context.Background(), "revenue", day, 5*day)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []time.Time{today.AddDate(0, 0, -3)}
+	if len(gaps) != len(want) || !gaps[0].Equal(want[0]) {
+		t.Error("Unexpected gaps", gaps, want)
+	}
+
+	for _, interval := range []time.Duration{0, -day} {
+		if _, err := client.DetectGaps(context.Background(), "revenue", interval, 5*day); err == nil {
+			t.Error("Invalid interval must fail", interval)
+		}
+	}
+}
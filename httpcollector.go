@@ -0,0 +1,169 @@
+package databox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HTTPMapping extracts one KPI from a scraped response.
+type HTTPMapping struct {
+	// Key is the metric key of the KPI.
+	Key string
+	// Path selects the value in a JSON response, e.g. "$.data.items[0].count".
+	// Object fields are separated by dots, array items are selected by index
+	// in brackets. The leading "$" is optional.
+	Path string
+	// Regexp is applied to the response body when Path is empty. The first
+	// submatch, or the whole match if there is no group, holds the value.
+	Regexp *regexp.Regexp
+	// Unit is set to the KPI.
+	Unit string
+}
+
+// HTTPCollector fetches a JSON or plaintext endpoint and extracts KPIs from
+// it according to Mappings. Use Client.CollectEvery to push them on an
+// interval.
+type HTTPCollector struct {
+	// URL of the scraped endpoint.
+	URL string
+	// Header is sent with every request.
+	Header http.Header
+	// HTTPClient is used to fetch the endpoint. If nil, http.DefaultClient
+	// is used.
+	HTTPClient *http.Client
+	// Mappings describe the KPIs extracted from the response.
+	Mappings []HTTPMapping
+	// NumberFormat is used to parse values found in text.
+	NumberFormat NumberFormat
+}
+
+// Collect implements Collector.
+func (c *HTTPCollector) Collect(ctx context.Context) ([]KPI, error) {
+	body, err := c.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", c.URL, err)
+	}
+
+	var document interface{}
+	kpis := make([]KPI, 0, len(c.Mappings))
+	for _, mapping := range c.Mappings {
+		var value float32
+		if mapping.Path != "" {
+			if document == nil {
+				decoder := json.NewDecoder(bytes.NewReader(body))
+				decoder.UseNumber()
+				if err := decoder.Decode(&document); err != nil {
+					return nil, fmt.Errorf("can't unmarshal response[%s]: %w", string(body), err)
+				}
+			}
+			value, err = jsonPathValue(document, mapping.Path, c.NumberFormat)
+		} else {
+			value, err = regexpValue(body, mapping.Regexp, c.NumberFormat)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extracting %s: %w", mapping.Key, err)
+		}
+		kpis = append(kpis, KPI{Key: mapping.Key, Value: value, Unit: mapping.Unit})
+	}
+	return kpis, nil
+}
+
+func (c *HTTPCollector) fetch(ctx context.Context) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", c.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request object: %w", err)
+	}
+	for key, values := range c.Header {
+		request.Header[key] = values
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("executing HTTP request: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return data, nil
+}
+
+// jsonPath walks document along path and returns the node found there.
+func jsonPath(document interface{}, path string) (interface{}, error) {
+	steps := strings.ReplaceAll(strings.TrimPrefix(path, "$"), "[", ".[")
+	node := document
+	for _, step := range strings.Split(steps, ".") {
+		if step == "" {
+			continue
+		}
+		if strings.HasPrefix(step, "[") && strings.HasSuffix(step, "]") {
+			index, err := strconv.Atoi(step[1 : len(step)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %s", step)
+			}
+			items, ok := node.([]interface{})
+			if !ok || index < 0 || index >= len(items) {
+				return nil, fmt.Errorf("no item %s at %s", step, path)
+			}
+			node = items[index]
+			continue
+		}
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("no field %s at %s", step, path)
+		}
+		if node, ok = object[step]; !ok {
+			return nil, fmt.Errorf("no field %s at %s", step, path)
+		}
+	}
+	return node, nil
+}
+
+// jsonPathValue returns the numeric value found in document at path. String
+// values are parsed with format.
+func jsonPathValue(document interface{}, path string, format NumberFormat) (float32, error) {
+	node, err := jsonPath(document, path)
+	if err != nil {
+		return 0, err
+	}
+	switch value := node.(type) {
+	case json.Number:
+		f, err := value.Float64()
+		return float32(f), err
+	case string:
+		return format.ParseFloat(value)
+	}
+	return 0, fmt.Errorf("value %v at %s is not numeric", node, path)
+}
+
+// regexpValue finds the value in body using re and parses it with format.
+func regexpValue(body []byte, re *regexp.Regexp, format NumberFormat) (float32, error) {
+	if re == nil {
+		return 0, fmt.Errorf("neither path nor regexp is set")
+	}
+	match := re.FindSubmatch(body)
+	if match == nil {
+		return 0, fmt.Errorf("no match of %s", re)
+	}
+	text := match[0]
+	if len(match) > 1 {
+		text = match[1]
+	}
+	return format.ParseFloat(string(text))
+}
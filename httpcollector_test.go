@@ -0,0 +1,72 @@
+package databox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestHTTPCollector(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/status.json":
+			_, _ = w.Write([]byte(`{"data":{"queues":[{"depth":12},{"depth":"3.5"}]},"uptime":99.9}`))
+		case "/status.txt":
+			_, _ = w.Write([]byte("active connections: 291\nrequests: 7\n"))
+		}
+	}))
+	defer server.Close()
+
+	collector := &HTTPCollector{
+		URL:    server.URL + "/status.json",
+		Header: http.Header{"X-Api-Key": {"secret"}},
+		Mappings: []HTTPMapping{
+			{Key: "queue.first", Path: "$.data.queues[0].depth"},
+			{Key: "queue.second", Path: "data.queues[1].depth"},
+			{Key: "uptime", Path: "$.uptime", Unit: "%"},
+		},
+	}
+	kpis, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []KPI{
+		{Key: "queue.first", Value: 12},
+		{Key: "queue.second", Value: 3.5},
+		{Key: "uptime", Value: 99.9, Unit: "%"},
+	}
+	if !reflect.DeepEqual(kpis, want) {
+		t.Errorf("got %+v, want %+v", kpis, want)
+	}
+
+	collector.Mappings = []HTTPMapping{{Key: "missing", Path: "$.data.queues[5].depth"}}
+	if _, err := collector.Collect(context.Background()); err == nil {
+		t.Error("Missing value must fail")
+	}
+
+	collector.URL = server.URL + "/status.txt"
+	collector.Mappings = []HTTPMapping{
+		{Key: "connections", Regexp: regexp.MustCompile(`active connections: (\d+)`)},
+	}
+	kpis, err = collector.Collect(context.Background())
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(kpis) != 1 || kpis[0].Value != 291 {
+		t.Error("Unexpected KPIs", kpis)
+	}
+
+	collector.Header = nil
+	if _, err := collector.Collect(context.Background()); err == nil {
+		t.Error("Unauthorized response must fail")
+	}
+}
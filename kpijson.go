@@ -0,0 +1,75 @@
+package databox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalJSON encodes the KPI in the wire format of the push request, the
+// same object AppendJSON produces, so KPIs can be stored in queues, files or
+// logs and pushed later verbatim. NaN and infinite values are errors.
+func (kpi KPI) MarshalJSON() ([]byte, error) {
+	if kpi.Key != "" && !isFinite(kpi.Value) {
+		return nil, fmt.Errorf("unsupported value %v of metric %q", kpi.Value, kpi.Key)
+	}
+	for key, value := range kpi.Metrics {
+		if !isFinite(value) {
+			return nil, fmt.Errorf("unsupported value %v of metric %q", value, key)
+		}
+	}
+	return kpi.AppendJSON(nil), nil
+}
+
+// UnmarshalJSON decodes the KPI from the wire format of the push request.
+// "$"-prefixed fields are metrics, "date" and "unit" are the KPI fields and
+// everything else is an attribute. A single metric is decoded into Key and
+// Value; several are decoded into Metrics, which is pushed the same way.
+// Null leaves the KPI unchanged.
+func (kpi *KPI) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	decoded := KPI{}
+	metrics := make(map[string]float32)
+	for name, value := range fields {
+		switch {
+		case strings.HasPrefix(name, "$"):
+			number, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("metric %q: value must be a number, got %T", name[1:], value)
+			}
+			metrics[name[1:]] = float32(number)
+		case name == "date" || name == "unit":
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string, got %T", name, value)
+			}
+			if name == "date" {
+				decoded.Date = s
+			} else {
+				decoded.Unit = s
+			}
+		default:
+			if decoded.Attributes == nil {
+				decoded.Attributes = make(map[string]interface{})
+			}
+			decoded.Attributes[name] = value
+		}
+	}
+
+	if len(metrics) == 1 {
+		for key, value := range metrics {
+			decoded.Key, decoded.Value = key, value
+		}
+	} else if len(metrics) > 1 {
+		decoded.Metrics = metrics
+	}
+	*kpi = decoded
+	return nil
+}
@@ -0,0 +1,44 @@
+package databox
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestKPIJSON(t *testing.T) {
+	t.Parallel()
+
+	kpis := []KPI{
+		{Key: "sales", Value: 0.1, Date: "2020-01-01", Unit: "USD", Attributes: map[string]interface{}{"region": "eu", "count": 2.0}},
+		{Metrics: map[string]float32{"a": 1, "b": -2.5}},
+	}
+	data, err := json.Marshal(kpis)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	payload, err := JSONSerializer{}.Serialize(kpis, nil)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	assertJSONEqual(t, []byte(`{"data":`+string(data)+`}`), payload)
+
+	var decoded []KPI
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if !reflect.DeepEqual(decoded, kpis) {
+		t.Errorf("Round trip differs\nwant %#v\n got %#v", kpis, decoded)
+	}
+
+	if _, err := json.Marshal(KPI{Key: "nan", Value: float32(math.NaN())}); err == nil {
+		t.Error("NaN must fail")
+	}
+	for _, input := range []string{`[]`, `{"$a":"1"}`, `{"$a":1,"date":1}`} {
+		var kpi KPI
+		if err := json.Unmarshal([]byte(input), &kpi); err == nil {
+			t.Errorf("%s must fail, got %#v", input, kpi)
+		}
+	}
+}
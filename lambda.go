@@ -0,0 +1,27 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithLambdaFlush runs handler with a fresh Batch and flushes the batch
+// synchronously once handler returns, so metrics of a serverless function
+// invocation are sent before the runtime freezes the process. The batch is
+// flushed even if handler fails; the handler's error takes precedence.
+//
+// The client should be created once, outside of the function handler, so the
+// connections are reused across invocations.
+func WithLambdaFlush(ctx context.Context, client *Client, handler func(ctx context.Context, batch *Batch) error) error {
+	batch := client.NewBatch()
+	handlerErr := handler(ctx, batch)
+
+	_, flushErr := batch.Flush(ctx)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	if flushErr != nil {
+		return fmt.Errorf("flushing %d KPIs: %w", batch.Len(), flushErr)
+	}
+	return nil
+}
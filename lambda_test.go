@@ -0,0 +1,32 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWithLambdaFlush(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	handlerErr := errors.New("handler failed")
+	err := WithLambdaFlush(context.Background(), client, func(ctx context.Context, batch *Batch) error {
+		batch.Add(KPI{Key: "invocations", Value: 1})
+		batch.Add(KPI{Key: "errors", Value: 1})
+		return handlerErr
+	})
+	if !errors.Is(err, handlerErr) {
+		t.Error("Handler error must be returned", err)
+	}
+	if calls != 1 {
+		t.Error("Batch must be flushed once, got", calls)
+	}
+}
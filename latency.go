@@ -0,0 +1,81 @@
+package databox
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// defaultLatencyWindow is the sliding window of Stats latencies when
+	// Stats.LatencyWindow is not set.
+	defaultLatencyWindow = 5 * time.Minute
+	// maxLatencySamples bounds the memory used by latency samples; the
+	// oldest samples are dropped first.
+	maxLatencySamples = 4096
+)
+
+// LatencySummary summarizes latencies of the API requests within the
+// sliding window of Stats. The latency is measured from sending the request
+// to receiving the response headers, for every attempt including retries.
+type LatencySummary struct {
+	// Count is the number of requests in the window.
+	Count int
+	// P50, P90 and P99 are the percentiles of the latency.
+	P50, P90, P99 time.Duration
+	// Max is the highest latency.
+	Max time.Duration
+}
+
+type latencySample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// recordLatency adds a request latency to the window. It's a no-op on nil
+// Stats.
+func (s *Stats) recordLatency(latency time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := latencySample{at: time.Now(), latency: latency}
+	if len(s.latencies) < maxLatencySamples {
+		s.latencies = append(s.latencies, sample)
+		return
+	}
+	s.latencies[s.latencyNext] = sample
+	s.latencyNext = (s.latencyNext + 1) % maxLatencySamples
+}
+
+// latencySummary summarizes the samples within the window. It must be called
+// with s.mu held.
+func (s *Stats) latencySummary(now time.Time) LatencySummary {
+	window := s.LatencyWindow
+	if window <= 0 {
+		window = defaultLatencyWindow
+	}
+	since := now.Add(-window)
+	latencies := make([]time.Duration, 0, len(s.latencies))
+	for _, sample := range s.latencies {
+		if sample.at.After(since) {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	if len(latencies) == 0 {
+		return LatencySummary{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p int) time.Duration {
+		// nearest-rank method
+		rank := (p*len(latencies) + 99) / 100
+		return latencies[rank-1]
+	}
+	return LatencySummary{
+		Count: len(latencies),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   latencies[len(latencies)-1],
+	}
+}
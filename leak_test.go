@@ -0,0 +1,119 @@
+package databox
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goroutines returns the stacks of running goroutines keyed by their header,
+// e.g. "goroutine 7".
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	stacks := make(map[string]string)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		header := string(stack)
+		if i := strings.Index(header, " ["); i >= 0 {
+			header = header[:i]
+		}
+		stacks[header] = string(stack)
+	}
+	return stacks
+}
+
+// checkLeaks returns a function failing the test if goroutines started after
+// checkLeaks was called are still running. Goroutines are given a second to
+// exit. Tests using it must not be parallel.
+func checkLeaks(t *testing.T) func() {
+	before := goroutines()
+	return func() {
+		t.Helper()
+
+		var leaked []string
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			leaked = leaked[:0]
+			for header, stack := range goroutines() {
+				if _, ok := before[header]; !ok && !strings.Contains(stack, "databox-go.goroutines(") {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+		}
+		t.Errorf("%d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	}
+}
+
+func TestClientCloseLeaks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"someRandomId"}`))
+	}))
+	defer server.Close()
+
+	check := checkLeaks(t)
+	for i := 0; i < 3; i++ {
+		client := NewClient(getToken(), WithKeyPrefix("leak."))
+		client.PushHost = server.URL
+		if _, err := client.InsertAll(context.Background(), []KPI{{Key: "a", Value: 1}}, false); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+		if _, err := client.LastPushes(1); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+	}
+	check()
+}
+
+func TestLoopsLeaks(t *testing.T) {
+	client := NewClient(getToken())
+	client.Backoff = ConstantBackoff{Delay: time.Hour, MaxRetries: 1}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(500, `{"type":"error","message":"retry me"}`), nil
+	})
+
+	check := checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	collector := CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+		return []KPI{{Key: "a", Value: 1}}, nil
+	})
+	load := func(ctx context.Context) (Config, error) {
+		return client.config(), nil
+	}
+	relay := &OutboxRelay{Pusher: client, Store: &memoryOutbox{}}
+	done := make(chan struct{})
+	loops := []func(){
+		func() { _ = client.CollectEvery(ctx, time.Millisecond, collector, nil) },
+		func() { _ = client.ReloadEvery(ctx, time.Millisecond, load, nil) },
+		func() { _ = relay.RelayEvery(ctx, time.Millisecond, nil) },
+		// Waits for the hour-long retry delay until cancelled.
+		func() { _, _ = client.InsertAll(ctx, []KPI{{Key: "b", Value: 1}}, false) },
+	}
+	for _, loop := range loops {
+		loop := loop
+		go func() {
+			loop()
+			done <- struct{}{}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	for range loops {
+		<-done
+	}
+	check()
+}
@@ -0,0 +1,224 @@
+package databox
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseLineProtocol converts points in InfluxDB line protocol to KPIs, e.g.
+//
+//	cpu,host=a,region=eu usage_user=12.5,usage_system=3i 1577836800000000000
+//
+// Every line results in one KPI. Its metrics are named measurement.field, or
+// just measurement for a field named "value". Tags become attributes and the
+// timestamp becomes the date. String and boolean fields are skipped.
+// Timestamps are interpreted in given precision, nanoseconds if zero.
+// Lines are limited to 16 MiB.
+func ParseLineProtocol(r io.Reader, precision time.Duration) ([]KPI, error) {
+	if precision <= 0 {
+		precision = time.Nanosecond
+	}
+
+	var kpis []KPI
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineProtocolLine)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kpi, err := parseLine(line, precision)
+		if err != nil {
+			if err := scanner.Err(); err != nil {
+				// The line was cut by the read error.
+				return nil, err
+			}
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if len(kpi.Metrics) > 0 {
+			kpis = append(kpis, kpi)
+		}
+	}
+	return kpis, scanner.Err()
+}
+
+func parseLine(line string, precision time.Duration) (KPI, error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) < 2 || len(sections) > 3 {
+		return KPI{}, fmt.Errorf("expected measurement, fields and optional timestamp")
+	}
+
+	kpi := KPI{Metrics: make(map[string]float32)}
+	series := splitUnescaped(sections[0], ',')
+	measurement := unescapeLineProtocol(series[0])
+	for _, tag := range series[1:] {
+		pair := splitUnescaped(tag, '=')
+		if len(pair) != 2 {
+			return KPI{}, fmt.Errorf("invalid tag %q", tag)
+		}
+		if kpi.Attributes == nil {
+			kpi.Attributes = make(map[string]interface{})
+		}
+		kpi.Attributes[unescapeLineProtocol(pair[0])] = unescapeLineProtocol(pair[1])
+	}
+
+	for _, field := range splitUnescaped(sections[1], ',') {
+		pair := splitUnescaped(field, '=')
+		if len(pair) != 2 {
+			return KPI{}, fmt.Errorf("invalid field %q", field)
+		}
+		value, ok, err := parseFieldValue(pair[1])
+		if err != nil {
+			return KPI{}, fmt.Errorf("field %q: %w", field, err)
+		}
+		if !ok {
+			continue
+		}
+		key := measurement + "." + unescapeLineProtocol(pair[0])
+		if pair[0] == "value" {
+			key = measurement
+		}
+		kpi.Metrics[key] = value
+	}
+
+	if len(sections) == 3 {
+		timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return KPI{}, fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+		date := time.Unix(0, timestamp*int64(precision)).UTC()
+		kpi.Date = date.Format(DateTimeTZFormat)
+	}
+	return kpi, nil
+}
+
+// parseFieldValue returns numeric value of the field. Strings and booleans
+// aren't numeric, ok is false for them.
+func parseFieldValue(value string) (float32, bool, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return 0, false, nil
+	case value == "t" || value == "T" || value == "f" || value == "F" ||
+		strings.EqualFold(value, "true") || strings.EqualFold(value, "false"):
+		return 0, false, nil
+	case strings.HasSuffix(value, "i"), strings.HasSuffix(value, "u"):
+		value = value[:len(value)-1]
+	}
+	f, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return 0, false, err
+	}
+	return float32(f), true, nil
+}
+
+// splitUnescaped splits s at separators not escaped by backslash and not
+// within double quotes.
+func splitUnescaped(s string, separator byte) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == separator && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescapeLineProtocol(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// maxLineProtocolLine is the longest line ParseLineProtocol accepts.
+const maxLineProtocolLine = 16 << 20
+
+// lineProtocolPrecisions maps the precision query parameter of InfluxDB's
+// write APIs, both 1.x and 2.x, to durations.
+var lineProtocolPrecisions = map[string]time.Duration{
+	"": time.Nanosecond, "n": time.Nanosecond, "ns": time.Nanosecond,
+	"u": time.Microsecond, "us": time.Microsecond, "ms": time.Millisecond,
+	"s": time.Second, "m": time.Minute, "h": time.Hour,
+}
+
+// LineProtocolHandler is an http.Handler accepting writes in InfluxDB line
+// protocol, e.g. from Telegraf's influxdb output, and pushing them as KPIs.
+// The precision query parameter (n, ns, u, us, ms, s, m, h) of InfluxDB's
+// write API is honoured, and gzip compressed bodies are accepted.
+type LineProtocolHandler struct {
+	Client *Client
+	// MaxBodyBytes caps the size of the body, both compressed and
+	// decompressed. Zero means DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// ServeHTTP implements http.Handler. It responds 204 No Content when the
+// points are pushed, like InfluxDB does.
+func (h *LineProtocolHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	precision, ok := lineProtocolPrecisions[r.URL.Query().Get("precision")]
+	if !ok {
+		http.Error(w, "invalid precision", http.StatusBadRequest)
+		return
+	}
+
+	body := maxBytesReader(w, r.Body, h.MaxBodyBytes)
+	switch encoding := r.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		body = maxBytesReader(w, reader, h.MaxBodyBytes)
+	default:
+		http.Error(w, "unsupported content encoding "+encoding, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	kpis, err := ParseLineProtocol(body, precision)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(kpis) > 0 {
+		if _, err := h.Client.InsertAll(r.Context(), kpis, false); err != nil {
+			http.Error(w, "pushing KPIs failed", http.StatusBadGateway)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package databox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	t.Parallel()
+
+	input := `# comment
+cpu,host=server\ 1,region=eu usage_user=12.5,usage_system=3i,busy=t 1577836800000000000
+temperature value=21.5,label="kitchen, north"
+disk\,io reads=7u 1577836800
+`
+	kpis, err := ParseLineProtocol(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []KPI{
+		{
+			Metrics:    map[string]float32{"cpu.usage_user": 12.5, "cpu.usage_system": 3},
+			Attributes: map[string]interface{}{"host": "server 1", "region": "eu"},
+			Date:       "2020-01-01 00:00:00+00:00",
+		},
+		{Metrics: map[string]float32{"temperature": 21.5}},
+		{Metrics: map[string]float32{"disk,io.reads": 7}, Date: "1970-01-01 00:00:01+00:00"},
+	}
+	if !reflect.DeepEqual(kpis, want) {
+		t.Errorf("got %+v, want %+v", kpis, want)
+	}
+
+	kpis, err = ParseLineProtocol(strings.NewReader("load value=1 1577836800"), time.Second)
+	if err != nil || kpis[0].Date != "2020-01-01 00:00:00+00:00" {
+		t.Error("Precision must be applied", kpis, err)
+	}
+
+	for _, line := range []string{"cpu", "cpu usage", "cpu usage=x", "cpu,host usage=1", "cpu usage=1 yesterday"} {
+		if _, err := ParseLineProtocol(strings.NewReader(line), 0); err == nil {
+			t.Errorf("%q: must fail", line)
+		}
+	}
+}
+
+func TestLineProtocolHandler(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	handler := &LineProtocolHandler{Client: client}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/write?precision=s", strings.NewReader("load value=1 1577836800\n")))
+	if recorder.Code != http.StatusNoContent || calls != 1 {
+		t.Error("Expected 204 and a push", recorder.Code, calls)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/write?precision=d", strings.NewReader("load value=1\n")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Error("Expected 400, got", recorder.Code)
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write([]byte("load value=2 438291\n"))
+	_ = writer.Close()
+	request := httptest.NewRequest("POST", "/write?precision=h", &compressed)
+	request.Header.Set("Content-Encoding", "gzip")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent || calls != 2 {
+		t.Error("Expected 204 and a push", recorder.Code, calls)
+	}
+
+	long := "load value=1 " + strings.Repeat("0", 70000) + "1\n"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/write", strings.NewReader(long)))
+	if recorder.Code != http.StatusNoContent || calls != 3 {
+		t.Error("Lines over 64 KiB must be accepted", recorder.Code, calls)
+	}
+
+	handler.MaxBodyBytes = 10
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/write", strings.NewReader("load value=1 1577836800000000000\n")))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Error("Expected 413, got", recorder.Code)
+	}
+}
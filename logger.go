@@ -0,0 +1,24 @@
+package databox
+
+// Logger receives warnings of the client. *log.Logger satisfies Logger,
+// other loggers can be plugged by LoggerFunc.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as Logger.
+// It plugs in loggers without Printf method, e.g.
+//
+//	// go.uber.org/zap
+//	client.Logger = databox.LoggerFunc(zapLogger.Sugar().Warnf)
+//
+//	// github.com/go-logr/logr
+//	client.Logger = databox.LoggerFunc(func(format string, v ...interface{}) {
+//		logrLogger.Info(fmt.Sprintf(format, v...))
+//	})
+type LoggerFunc func(format string, v ...interface{})
+
+// Printf calls f(format, v...).
+func (f LoggerFunc) Printf(format string, v ...interface{}) {
+	f(format, v...)
+}
@@ -0,0 +1,25 @@
+package databox
+
+import (
+	"fmt"
+	"testing"
+)
+
+type loggerMock []string
+
+func (l *loggerMock) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestLoggerFunc(t *testing.T) {
+	t.Parallel()
+
+	var logged string
+	var logger Logger = LoggerFunc(func(format string, v ...interface{}) {
+		logged = fmt.Sprintf(format, v...)
+	})
+	logger.Printf("metric %q", "sales")
+	if logged != `metric "sales"` {
+		t.Error("Unexpected message", logged)
+	}
+}
@@ -0,0 +1,279 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a recurring period when pushes are suppressed, e.g.
+// while the data source is maintained or the dashboards are rebuilt.
+type MaintenanceWindow struct {
+	// Start is a cron expression of the window starts with five fields:
+	// minute, hour, day of month, month and day of week (0 or 7 is Sunday).
+	// Fields accept *, numbers, ranges, lists and steps, e.g. "0 2 * * 0"
+	// starts on Sundays at 2:00, "*/30 9-17 * * 1-5" every half an hour of
+	// working hours.
+	Start string
+	// Duration is the length of the window.
+	Duration time.Duration
+	// Location is the time zone of Start. Nil means UTC.
+	Location *time.Location
+}
+
+// DefaultMaxBuffered is the number of KPIs Maintenance buffers if
+// MaxBuffered is zero.
+const DefaultMaxBuffered = 10000
+
+// Maintenance suppresses pushes during maintenance windows. KPIs pushed
+// during a window are validated by a dry run of the pipeline, buffered and
+// pushed along with the first push after it, or dropped if Drop is set. Dry
+// runs bypass it. Set it to
+// Client.Maintenance. Maintenance is safe for concurrent use.
+type Maintenance struct {
+	Windows []MaintenanceWindow
+	// Drop discards KPIs pushed during the windows.
+	Drop bool
+	// MaxBuffered caps the number of buffered KPIs, the oldest are dropped
+	// beyond it. Zero means DefaultMaxBuffered.
+	MaxBuffered int
+
+	mu       sync.Mutex
+	buffered []KPI
+	// specs caches the parsed Start expressions.
+	specs map[string]*cronSpec
+	now   func() time.Time
+}
+
+// Active reports whether t falls within any of the windows.
+func (m *Maintenance) Active(t time.Time) (bool, error) {
+	for _, window := range m.Windows {
+		spec, err := m.spec(window.Start)
+		if err != nil {
+			return false, fmt.Errorf("maintenance window %q: %w", window.Start, err)
+		}
+		location := window.Location
+		if location == nil {
+			location = time.UTC
+		}
+		// Look for a start within the duration before t.
+		local := t.In(location)
+		if spec.prev(local, local.Add(-window.Duration)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// spec returns the parsed start, parsing each expression once.
+func (m *Maintenance) spec(start string) (*cronSpec, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if spec, ok := m.specs[start]; ok {
+		return spec, nil
+	}
+	spec, err := parseCron(start)
+	if err != nil {
+		return nil, err
+	}
+	if m.specs == nil {
+		m.specs = make(map[string]*cronSpec)
+	}
+	m.specs[start] = spec
+	return spec, nil
+}
+
+// Buffered returns the number of KPIs waiting for the end of the window.
+func (m *Maintenance) Buffered() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buffered)
+}
+
+// insertAll pushes kpis with c unless a window is active. Buffered KPIs are
+// pushed with the first push after the window, and buffered again if it
+// fails.
+func (m *Maintenance) insertAll(ctx context.Context, c *Client, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
+	now := time.Now()
+	if m.now != nil {
+		now = m.now()
+	}
+	active, err := m.Active(now)
+	if err != nil {
+		return nil, err
+	}
+
+	if active && !m.Drop {
+		// Invalid KPIs would fail every push after the window.
+		if _, _, err := c.prepare(WithDryRun(ctx), kpis); err != nil {
+			return nil, err
+		}
+	}
+	m.mu.Lock()
+	if active {
+		var dropped int
+		if !m.Drop {
+			// The attributes of ctx wouldn't apply to the later push.
+			dropped = m.buffer(withContextAttributes(ctx, kpis))
+		}
+		m.mu.Unlock()
+		if dropped > 0 && c.Logger != nil {
+			c.Logger.Printf("databox: maintenance buffer full, dropped %d KPIs", dropped)
+		}
+		trace(ctx, "maintenance", kpis, "", nil)
+		return &ResponseStatus{}, nil
+	}
+	buffered := m.buffered
+	m.buffered = nil
+	m.mu.Unlock()
+
+	if len(buffered) == 0 {
+		return c.insertAll(ctx, kpis, forcePush)
+	}
+	responseStatus, err := c.insertAll(ctx, append(buffered[:len(buffered):len(buffered)], kpis...), forcePush)
+	if err != nil {
+		if !IsRetryable(err) && c.Logger != nil {
+			c.Logger.Printf("databox: push after maintenance failed, %d buffered KPIs kept: %v", len(buffered), err)
+		}
+		m.mu.Lock()
+		m.buffered, buffered = buffered, m.buffered
+		dropped := m.buffer(buffered)
+		m.mu.Unlock()
+		if dropped > 0 && c.Logger != nil {
+			c.Logger.Printf("databox: maintenance buffer full, dropped %d KPIs", dropped)
+		}
+	}
+	return responseStatus, err
+}
+
+// buffer appends kpis to the buffer, dropping the oldest KPIs beyond
+// MaxBuffered. It returns the number of dropped KPIs. m.mu must be held.
+func (m *Maintenance) buffer(kpis []KPI) int {
+	max := m.MaxBuffered
+	if max <= 0 {
+		max = DefaultMaxBuffered
+	}
+	m.buffered = append(m.buffered, kpis...)
+	dropped := len(m.buffered) - max
+	if dropped <= 0 {
+		return 0
+	}
+	m.buffered = append(m.buffered[:0:0], m.buffered[dropped:]...)
+	return dropped
+}
+
+// cronSpec is a parsed cron expression; bit n of a field is set if the value
+// n matches.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is set if day of month or day of week is *, and both must
+	// match then. Otherwise either of them matches, as in cron.
+	anyDay bool
+}
+
+func parseCron(spec string) (*cronSpec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	var c cronSpec
+	var err error
+	ranges := []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	}
+	for i, r := range ranges {
+		if *r.bits, err = parseCronField(fields[i], r.min, r.max); err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDay = fields[2] == "*" || fields[4] == "*"
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			part = part[:i]
+		}
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for n := from; n <= to; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+	return bits, nil
+}
+
+// prev reports whether the spec matches a minute in (since, t]. It walks
+// back from t skipping whole months, days and hours that don't match.
+func (c *cronSpec) prev(t, since time.Time) bool {
+	location := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, location)
+	for t.After(since) {
+		year, month, day := t.Date()
+		switch {
+		case c.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month, 1, 0, 0, 0, 0, location).Add(-time.Minute)
+		case !c.matchesDay(t):
+			t = time.Date(year, month, day, 0, 0, 0, 0, location).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour(), 0, 0, 0, location).Add(-time.Minute)
+		default:
+			// The latest matching minute of the hour up to t.
+			minutes := c.minute & (1<<uint(t.Minute()+1) - 1)
+			hour := time.Date(year, month, day, t.Hour(), 0, 0, 0, location)
+			if minutes == 0 {
+				t = hour.Add(-time.Minute)
+				continue
+			}
+			return hour.Add(time.Duration(bits.Len64(minutes)-1) * time.Minute).After(since)
+		}
+	}
+	return false
+}
+
+func (c *cronSpec) matchesDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,149 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceActive(t *testing.T) {
+	t.Parallel()
+
+	prague, _ := time.LoadLocation("Europe/Prague")
+	maintenance := &Maintenance{Windows: []MaintenanceWindow{
+		{Start: "0 2 * * 0", Duration: time.Hour},
+		{Start: "*/30 9-17 1,15 * *", Duration: 5 * time.Minute, Location: prague},
+		{Start: "45 23 31 12 *", Duration: 2 * time.Hour},
+	}}
+	tests := []struct {
+		time   time.Time
+		active bool
+	}{
+		{time.Date(2020, 1, 5, 2, 0, 0, 0, time.UTC), true},   // Sunday
+		{time.Date(2020, 1, 5, 2, 59, 59, 0, time.UTC), true}, // Sunday
+		{time.Date(2020, 1, 5, 3, 0, 0, 0, time.UTC), false},
+		{time.Date(2020, 1, 5, 1, 59, 0, 0, time.UTC), false},
+		{time.Date(2020, 1, 6, 2, 30, 0, 0, time.UTC), false}, // Monday
+		{time.Date(2020, 1, 15, 8, 34, 0, 0, time.UTC), true}, // 9:34 in Prague
+		{time.Date(2020, 1, 15, 8, 36, 0, 0, time.UTC), false},
+		{time.Date(2020, 1, 16, 8, 34, 0, 0, time.UTC), false},
+		{time.Date(2021, 1, 1, 1, 44, 0, 0, time.UTC), true}, // Spans the new year
+		{time.Date(2021, 1, 1, 1, 45, 0, 0, time.UTC), false},
+		{time.Date(2020, 12, 31, 23, 44, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if active, err := maintenance.Active(test.time); err != nil || active != test.active {
+			t.Errorf("%v: expected %v, got %v (%v)", test.time, test.active, active, err)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		maintenance := &Maintenance{Windows: []MaintenanceWindow{{Start: spec, Duration: time.Minute}}}
+		if _, err := maintenance.Active(time.Now()); err == nil {
+			t.Errorf("%q must be invalid", spec)
+		}
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	var logger loggerMock
+	statusCode := 200
+	client := NewClient(getToken())
+	client.Logger = &logger
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(statusCode, `{"id":"someRandomId","type":"error","message":"fail"}`), nil
+	})
+	now := time.Date(2020, 1, 5, 2, 30, 0, 0, time.UTC)
+	client.Maintenance = &Maintenance{
+		Windows: []MaintenanceWindow{{Start: "0 2 * * *", Duration: time.Hour}},
+		now:     func() time.Time { return now },
+	}
+
+	ctx := WithAttributes(context.Background(), map[string]interface{}{"job": "import"})
+	if _, err := client.InsertAll(ctx, []KPI{{Key: "a", Value: 1}}, false); err != nil || len(requests) != 0 {
+		t.Fatal("Push must be buffered", err, requests)
+	}
+	if client.Maintenance.Buffered() != 1 {
+		t.Error("Expected buffered KPI")
+	}
+
+	now = now.Add(time.Hour)
+	statusCode = 500
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "b", Value: 2}}, false); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	if client.Maintenance.Buffered() != 1 {
+		t.Error("Buffered KPIs must be kept when the push fails")
+	}
+	statusCode = 200
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "c", Value: 3}}, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if last := requests[len(requests)-1]; len(last.Data) != 2 || last.Data[0]["$a"] != 1.0 || last.Data[0]["job"] != "import" || last.Data[1]["$c"] != 3.0 {
+		t.Error("Buffered KPIs must be pushed after the window", last)
+	}
+
+	now = now.Add(-time.Hour)
+	n := len(requests)
+	if _, err := client.InsertAll(WithDryRun(context.Background()), []KPI{{Key: "e", Value: 5}}, false); err != nil || len(requests) != n || client.Maintenance.Buffered() != 0 {
+		t.Error("Dry runs must bypass the window", err)
+	}
+	client.Maintenance.MaxBuffered = 2
+	for i := 0; i < 3; i++ {
+		if _, err := client.InsertAll(context.Background(), []KPI{{Key: "f", Value: float32(i)}}, false); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+	}
+	if client.Maintenance.Buffered() != 2 {
+		t.Error("Buffer must be capped", client.Maintenance.Buffered())
+	}
+	now = now.Add(time.Hour)
+	statusCode = 400
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "g", Value: 6}}, false); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	if last := requests[len(requests)-1]; len(last.Data) != 3 || last.Data[0]["$f"] != 1.0 {
+		t.Error("Oldest KPIs must be dropped", last)
+	}
+	if client.Maintenance.Buffered() != 2 || len(logger) != 2 {
+		t.Error("Buffered KPIs must be kept when the push is rejected", logger)
+	}
+	statusCode = 200
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "g", Value: 6}}, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if last := requests[len(requests)-1]; len(last.Data) != 3 || client.Maintenance.Buffered() != 0 {
+		t.Error("Kept KPIs must be pushed with the next push", last)
+	}
+
+	now = now.Add(-time.Hour)
+	client.Transforms = []Transform{TransformEach(func(kpi KPI) ([]KPI, error) {
+		if kpi.Key == "invalid" {
+			return nil, errors.New("invalid KPI")
+		}
+		return []KPI{kpi}, nil
+	})}
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "invalid", Value: 1}}, false); err == nil || client.Maintenance.Buffered() != 0 {
+		t.Error("Invalid KPIs must not be buffered", err)
+	}
+	client.Transforms = nil
+	now = now.Add(time.Hour)
+
+	now = now.Add(23 * time.Hour)
+	client.Maintenance.Drop = true
+	n = len(requests)
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "d", Value: 4}}, false); err != nil || len(requests) != n || client.Maintenance.Buffered() != 0 {
+		t.Error("Push must be dropped", err)
+	}
+}
@@ -0,0 +1,139 @@
+package databox
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidKey is returned when a metric key violates Client.NamingPolicy.
+var ErrInvalidKey = errors.New("invalid metric key")
+
+// NamingMode tells what happens to a key violating the NamingPolicy.
+type NamingMode int
+
+const (
+	// NamingError rejects the push with ErrInvalidKey.
+	NamingError NamingMode = iota
+	// NamingWarn logs the violation through Client.Logger and pushes the key
+	// unchanged.
+	NamingWarn
+	// NamingRewrite replaces the key by NamingPolicy.Rewrite.
+	NamingRewrite
+)
+
+// NamingPolicy enforces a naming convention of metric keys, e.g.
+// team_service_metric.
+type NamingPolicy struct {
+	// Pattern must match every key.
+	Pattern *regexp.Regexp
+	// Check validates the key, if set. It's used together with Pattern.
+	Check func(key string) error
+	// Mode tells what to do with violating keys.
+	Mode NamingMode
+	// Rewrite converts a violating key in NamingRewrite mode. If nil,
+	// SnakeCase is used. The rewritten key must pass the policy.
+	Rewrite func(key string) string
+}
+
+// validate returns the reason why key violates the policy, or nil.
+func (p *NamingPolicy) validate(key string) error {
+	if p.Pattern != nil && !p.Pattern.MatchString(key) {
+		return fmt.Errorf("%w %q: doesn't match %s", ErrInvalidKey, key, p.Pattern)
+	}
+	if p.Check != nil {
+		if err := p.check(key); err != nil {
+			if _, ok := err.(*PanicError); ok {
+				return err
+			}
+			return fmt.Errorf("%w %q: %v", ErrInvalidKey, key, err)
+		}
+	}
+	return nil
+}
+
+// apply returns key conforming to the policy.
+func (p *NamingPolicy) apply(key string, logger Logger) (string, error) {
+	err := p.validate(key)
+	if err == nil {
+		return key, nil
+	}
+	if _, ok := err.(*PanicError); ok {
+		return "", err
+	}
+	switch p.Mode {
+	case NamingWarn:
+		if logger != nil {
+			logger.Printf("databox: %v", err)
+		}
+		return key, nil
+	case NamingRewrite:
+		rewrite := p.Rewrite
+		if rewrite == nil {
+			rewrite = SnakeCase
+		}
+		rewritten, err := rewriteKey(rewrite, key)
+		if err != nil {
+			return "", err
+		}
+		if err := p.validate(rewritten); err != nil {
+			return "", fmt.Errorf("rewriting %q: %w", key, err)
+		}
+		return rewritten, nil
+	}
+	return "", err
+}
+
+// applyNamingPolicy returns kpis with keys conforming to c.NamingPolicy. The
+// caller's KPIs aren't modified.
+func (c *Client) applyNamingPolicy(kpis []KPI) ([]KPI, error) {
+	if c.NamingPolicy == nil {
+		return kpis, nil
+	}
+
+	applied := make([]KPI, len(kpis))
+	for i, kpi := range kpis {
+		if kpi.Key != "" {
+			key, err := c.NamingPolicy.apply(kpi.Key, c.Logger)
+			if err != nil {
+				return nil, err
+			}
+			kpi.Key = key
+		}
+		if len(kpi.Metrics) > 0 {
+			metrics := make(map[string]float32, len(kpi.Metrics))
+			for key, value := range kpi.Metrics {
+				key, err := c.NamingPolicy.apply(key, c.Logger)
+				if err != nil {
+					return nil, err
+				}
+				metrics[key] = value
+			}
+			kpi.Metrics = metrics
+		}
+		applied[i] = kpi
+	}
+	return applied, nil
+}
+
+// SnakeCase lowercases key and replaces every run of characters other than
+// letters and digits by a single underscore, e.g. "Sales.Total EUR" becomes
+// "sales_total_eur".
+func SnakeCase(key string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+			underscore = false
+			continue
+		}
+		if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
@@ -0,0 +1,75 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestNamingPolicy(t *testing.T) {
+	t.Parallel()
+
+	var pushed KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &pushed)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	policy := &NamingPolicy{Pattern: regexp.MustCompile(`^[a-z]+_[a-z]+_[a-z_]+$`)}
+	client.NamingPolicy = policy
+	kpis := []KPI{{Key: "Sales.Total EUR", Value: 1}, {Metrics: map[string]float32{"ops_api_latency": 2}}}
+
+	if _, err := client.InsertAll(context.Background(), kpis, false); !errors.Is(err, ErrInvalidKey) {
+		t.Error("Expected ErrInvalidKey", err)
+	}
+
+	var logger loggerMock
+	client.Logger = &logger
+	policy.Mode = NamingWarn
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Error("Must be nil", err)
+	}
+	if len(logger) != 1 || pushed.Data[0]["$Sales.Total EUR"] == nil {
+		t.Error("Violation must be logged and key kept", logger, pushed.Data)
+	}
+
+	policy.Mode = NamingRewrite
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Error("Must be nil", err)
+	}
+	if pushed.Data[0]["$sales_total_eur"] == nil || pushed.Data[1]["$ops_api_latency"] == nil {
+		t.Error("Key must be rewritten", pushed.Data)
+	}
+	if kpis[0].Key != "Sales.Total EUR" {
+		t.Error("KPI must not be modified")
+	}
+
+	policy.Check = func(key string) error {
+		if len(key) > 10 {
+			return errors.New("too long")
+		}
+		return nil
+	}
+	if _, err := client.InsertAll(context.Background(), kpis, false); !errors.Is(err, ErrInvalidKey) {
+		t.Error("Rewritten key must pass the policy", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	t.Parallel()
+
+	for key, want := range map[string]string{
+		"Sales.Total EUR":  "sales_total_eur",
+		"  api--latency  ": "api_latency",
+		"ok_key":           "ok_key",
+	} {
+		if got := SnakeCase(key); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package databox
+
+import "strings"
+
+// Normalizer normalizes an attribute value, e.g. to keep "US", "us" and
+// " USA " one drill-down item on dashboards.
+type Normalizer func(value interface{}) interface{}
+
+// NormalizeAttributes returns a Transform applying the normalizers to the
+// attributes of the same name.
+func NormalizeAttributes(normalizers map[string]Normalizer) Transform {
+	return TransformEach(func(kpi KPI) ([]KPI, error) {
+		var attributes map[string]interface{}
+		for name, value := range kpi.Attributes {
+			normalize, ok := normalizers[name]
+			if !ok {
+				continue
+			}
+			if attributes == nil {
+				attributes = make(map[string]interface{}, len(kpi.Attributes))
+				for name, value := range kpi.Attributes {
+					attributes[name] = value
+				}
+			}
+			attributes[name] = normalize(value)
+		}
+		if attributes != nil {
+			kpi.Attributes = attributes
+		}
+		return []KPI{kpi}, nil
+	})
+}
+
+// ChainNormalizers composes normalizers into one, applied in order.
+func ChainNormalizers(normalizers ...Normalizer) Normalizer {
+	return func(value interface{}) interface{} {
+		for _, normalize := range normalizers {
+			value = normalize(value)
+		}
+		return value
+	}
+}
+
+// Lowercase is a Normalizer lowercasing string values.
+func Lowercase(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// TrimSpace is a Normalizer removing leading and trailing white space of
+// string values.
+func TrimSpace(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return value
+}
+
+// MapValues returns a Normalizer replacing string values found in mapping,
+// e.g. {"usa": "us"}. Other values are kept.
+func MapValues(mapping map[string]string) Normalizer {
+	return func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			if mapped, ok := mapping[s]; ok {
+				return mapped
+			}
+		}
+		return value
+	}
+}
@@ -0,0 +1,33 @@
+package databox
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeAttributes(t *testing.T) {
+	t.Parallel()
+
+	transform := NormalizeAttributes(map[string]Normalizer{
+		"country": ChainNormalizers(TrimSpace, Lowercase, MapValues(map[string]string{"usa": "us"})),
+		"plan":    Lowercase,
+	})
+	kpis := []KPI{
+		{Key: "a", Attributes: map[string]interface{}{"country": " USA ", "plan": "Pro", "shop": "Eshop"}},
+		{Key: "a", Attributes: map[string]interface{}{"country": "US", "plan": 1}},
+		{Key: "a"},
+	}
+	result, err := transform(context.Background(), kpis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[0].Attributes["country"] != "us" || result[0].Attributes["plan"] != "pro" || result[0].Attributes["shop"] != "Eshop" {
+		t.Error("Unexpected attributes", result[0].Attributes)
+	}
+	if result[1].Attributes["country"] != "us" || result[1].Attributes["plan"] != 1 {
+		t.Error("Unexpected attributes", result[1].Attributes)
+	}
+	if kpis[0].Attributes["country"] != " USA " {
+		t.Error("KPIs of the caller must not be modified")
+	}
+}
@@ -0,0 +1,43 @@
+package databox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat describes how numbers are written in text values, e.g.
+// "1.234,56" in European exports is NumberFormat{Decimal: ',', Thousands: '.'}.
+// The zero value parses numbers like strconv.ParseFloat.
+type NumberFormat struct {
+	// Decimal separator. Zero means '.'.
+	Decimal rune
+	// Thousands separator, e.g. ',', '.', ' ' or '\u00a0'. Zero means none.
+	Thousands rune
+}
+
+// ParseFloat parses s, surrounded by optional white space. A '.' is rejected
+// unless it's one of the separators, so "1.234" can't be read as 1.234 when
+// the decimal separator is ','.
+func (f NumberFormat) ParseFloat(s string) (float32, error) {
+	s = strings.TrimSpace(s)
+	decimal := f.Decimal
+	if decimal == 0 {
+		decimal = '.'
+	}
+	if decimal != '.' || f.Thousands != 0 {
+		text := s
+		if f.Thousands != 0 {
+			text = strings.ReplaceAll(text, string(f.Thousands), "")
+		}
+		if decimal != '.' {
+			if strings.ContainsRune(text, '.') {
+				return 0, fmt.Errorf("unexpected '.' in number %q", s)
+			}
+			text = strings.Replace(text, string(decimal), ".", 1)
+		}
+		s = text
+	}
+	value, err := strconv.ParseFloat(s, 32)
+	return float32(value), err
+}
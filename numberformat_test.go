@@ -0,0 +1,37 @@
+package databox
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNumberFormat(t *testing.T) {
+	t.Parallel()
+
+	european := NumberFormat{Decimal: ',', Thousands: '.'}
+	tests := []struct {
+		format NumberFormat
+		text   string
+		want   float32
+		err    bool
+	}{
+		{NumberFormat{}, " 1234.56 ", 1234.56, false},
+		{NumberFormat{}, "1,234.56", 0, true},
+		{NumberFormat{Thousands: ','}, "1,234.56", 1234.56, false},
+		{european, "1.234,56", 1234.56, false},
+		{european, "-0,5", -0.5, false},
+		{NumberFormat{Decimal: ','}, "1.234", 0, true},
+		{NumberFormat{Decimal: ',', Thousands: ' '}, "1 234 567,8", 1234567.8, false},
+	}
+	for _, test := range tests {
+		got, err := test.format.ParseFloat(test.text)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("%q: got %v, %v", test.text, got, err)
+		}
+	}
+
+	value, err := regexpValue([]byte("revenue: 1.234,56 EUR"), regexp.MustCompile(`revenue: ([\d.,]+)`), european)
+	if err != nil || value != 1234.56 {
+		t.Error("Regexp value must be parsed with the format", value, err)
+	}
+}
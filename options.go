@@ -0,0 +1,92 @@
+package databox
+
+import "context"
+
+// Option configures the Client created by NewClient. The transport options
+// have no effect if the Client's transport is replaced, nor on copies made by
+// With; create another Client with NewClient for different transport
+// settings.
+type Option func(*Client)
+
+// With returns a shallow copy of c modified by opts, e.g. a client of
+// another data source. The copy shares the HTTP client and transport of c, so
+// their connections are pooled together and closed by c.Close, and the
+// stateful helpers like Stats, Receipts or DedupCache. Transport options are
+// ignored by the copy.
+func (c *Client) With(opts ...Option) *Client {
+	config := c.config()
+	clone := &Client{
+		PushToken:        config.PushToken,
+		PushHost:         config.PushHost,
+		Auth:             c.Auth,
+		VerifyPushes:     c.VerifyPushes,
+		Backoff:          c.Backoff,
+		RetryBudget:      config.RetryBudget,
+		NamingPolicy:     c.NamingPolicy,
+		Schema:           c.Schema,
+		AnomalyGuard:     c.AnomalyGuard,
+		UnitChecker:      c.UnitChecker,
+		DedupCache:       c.DedupCache,
+		Downsampler:      c.Downsampler,
+		Maintenance:      c.Maintenance,
+		Transforms:       c.Transforms,
+		OnCompaction:     c.OnCompaction,
+		ReviewDiff:       c.ReviewDiff,
+		Stats:            c.Stats,
+		Receipts:         c.Receipts,
+		StrictDecoding:   c.StrictDecoding,
+		SignRequest:      c.SignRequest,
+		Serializer:       c.Serializer,
+		Codec:            c.Codec,
+		Sequence:         c.Sequence,
+		Precision:        c.Precision,
+		MaxResponseBytes: c.MaxResponseBytes,
+		Logger:           c.Logger,
+		HTTPClient:       c.HTTPClient,
+	}
+	for _, opt := range opts {
+		opt(clone)
+	}
+	return clone
+}
+
+// WithToken sets the push token.
+func WithToken(pushToken string) Option {
+	return func(c *Client) {
+		c.PushToken = pushToken
+	}
+}
+
+// WithKeyPrefix prefixes the metric keys of the pushed KPIs, e.g. with
+// "billing.".
+func WithKeyPrefix(prefix string) Option {
+	return WithTransforms(TransformEach(func(kpi KPI) ([]KPI, error) {
+		if kpi.Key != "" {
+			kpi.Key = prefix + kpi.Key
+		}
+		if len(kpi.Metrics) > 0 {
+			metrics := make(map[string]float32, len(kpi.Metrics))
+			for key, value := range kpi.Metrics {
+				metrics[prefix+key] = value
+			}
+			kpi.Metrics = metrics
+		}
+		return []KPI{kpi}, nil
+	}))
+}
+
+// WithDefaultAttributes adds the attributes to the pushed KPIs, unless the
+// KPIs set them.
+func WithDefaultAttributes(attributes map[string]interface{}) Option {
+	return WithTransforms(func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		return withAttributes(kpis, attributes, false), nil
+	})
+}
+
+// WithTransforms appends transforms to the Client's Transforms.
+func WithTransforms(transforms ...Transform) Option {
+	return func(c *Client) {
+		// Don't append to the array shared with the Client copied by With.
+		c.Transforms = append(c.Transforms[:len(c.Transforms):len(c.Transforms)], transforms...)
+	}
+}
@@ -0,0 +1,58 @@
+package databox
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientWith(t *testing.T) {
+	t.Parallel()
+
+	var bodies, tokens []string
+	client := NewClient("parent")
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		token, _, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		tokens = append(tokens, token)
+		bodies = append(bodies, string(body))
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	client.Transforms = make([]Transform, 0, 4)
+
+	derived := client.With(
+		WithToken("child"),
+		WithKeyPrefix("billing."),
+		WithDefaultAttributes(map[string]interface{}{"source": "billing", "region": "eu"}),
+	)
+	kpi := KPI{Key: "revenue", Value: 1, Attributes: map[string]interface{}{"region": "us"}}
+	if _, err := derived.PushCtx(t.Context(), &kpi); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if _, err := client.PushCtx(t.Context(), &kpi); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+
+	if tokens[0] != "child" || !strings.Contains(bodies[0], `"$billing.revenue":1`) || !strings.Contains(bodies[0], `"source":"billing"`) || !strings.Contains(bodies[0], `"region":"us"`) {
+		t.Error("Derived client must apply its options", tokens[0], bodies[0])
+	}
+	if tokens[1] != "parent" || bodies[1] != `{"data":[{"$revenue":1,"region":"us"}]}` {
+		t.Error("Parent client must not change", tokens[1], bodies[1])
+	}
+	if derived.HTTPClient != client.HTTPClient {
+		t.Error("HTTP client must be shared")
+	}
+
+	// Every exported field is copied.
+	copied := reflect.ValueOf(client.With()).Elem()
+	original := reflect.ValueOf(client).Elem()
+	for i := 0; i < original.NumField(); i++ {
+		field := original.Type().Field(i)
+		if field.IsExported() && fmt.Sprint(copied.Field(i)) != fmt.Sprint(original.Field(i)) {
+			t.Errorf("Field %s isn't copied", field.Name)
+		}
+	}
+}
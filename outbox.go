@@ -0,0 +1,207 @@
+package databox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutboxEntry is a group of KPIs saved to an outbox together.
+type OutboxEntry struct {
+	ID   int64
+	KPIs []KPI
+}
+
+// OutboxStore holds KPIs waiting for OutboxRelay. Saving is specific to the
+// store, as it joins the caller's transaction, see SQLOutbox.
+type OutboxStore interface {
+	// Pending returns up to limit entries not marked as sent, oldest first.
+	Pending(ctx context.Context, limit int) ([]OutboxEntry, error)
+	// MarkSent marks the entries as sent.
+	MarkSent(ctx context.Context, ids []int64) error
+}
+
+// SQLOutbox is an OutboxStore in a table of the application's database, so
+// KPIs are saved atomically with the business data they describe. The table
+// is created by the application, e.g.:
+//
+//	CREATE TABLE databox_outbox (
+//		id      BIGSERIAL PRIMARY KEY,
+//		kpis    TEXT NOT NULL,
+//		sent_at TIMESTAMP NULL
+//	)
+type SQLOutbox struct {
+	DB *sql.DB
+	// Table is the outbox table. Empty means "databox_outbox".
+	Table string
+	// Placeholder returns the query placeholder of the n-th argument,
+	// counted from 1. Nil means "?"; PostgreSQL needs "$1", "$2", ...
+	Placeholder func(n int) string
+}
+
+var _ OutboxStore = (*SQLOutbox)(nil)
+
+// outboxKPI is the stored form of KPI.
+type outboxKPI struct {
+	Key        string                 `json:"key,omitempty"`
+	Value      float32                `json:"value,omitempty"`
+	Metrics    map[string]float32     `json:"metrics,omitempty"`
+	Date       string                 `json:"date,omitempty"`
+	Unit       string                 `json:"unit,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SaveToOutbox writes kpis to the outbox within the caller's transaction. They
+// are pushed by OutboxRelay once the transaction is committed, and never if it
+// is rolled back.
+func (o *SQLOutbox) SaveToOutbox(ctx context.Context, tx *sql.Tx, kpis []KPI) error {
+	if len(kpis) == 0 {
+		return nil
+	}
+	stored := make([]outboxKPI, len(kpis))
+	for i, kpi := range kpis {
+		stored[i] = outboxKPI(kpi)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encoding KPIs: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (kpis) VALUES (%s)", o.table(), o.placeholder(1))
+	_, err = tx.ExecContext(ctx, query, string(data))
+	return err
+}
+
+// Pending implements OutboxStore.
+func (o *SQLOutbox) Pending(ctx context.Context, limit int) ([]OutboxEntry, error) {
+	query := fmt.Sprintf("SELECT id, kpis FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT %d", o.table(), limit)
+	rows, err := o.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var entry OutboxEntry
+		var data string
+		if err := rows.Scan(&entry.ID, &data); err != nil {
+			return nil, err
+		}
+		var stored []outboxKPI
+		if err := json.Unmarshal([]byte(data), &stored); err != nil {
+			return nil, fmt.Errorf("decoding outbox entry %d: %w", entry.ID, err)
+		}
+		for _, kpi := range stored {
+			entry.KPIs = append(entry.KPIs, KPI(kpi))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// MarkSent implements OutboxStore.
+func (o *SQLOutbox) MarkSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := []interface{}{time.Now().UTC()}
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = o.placeholder(i + 2)
+	}
+	query := fmt.Sprintf("UPDATE %s SET sent_at = %s WHERE id IN (%s)", o.table(), o.placeholder(1), strings.Join(placeholders, ", "))
+	_, err := o.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (o *SQLOutbox) table() string {
+	if o.Table == "" {
+		return "databox_outbox"
+	}
+	return o.Table
+}
+
+func (o *SQLOutbox) placeholder(n int) string {
+	if o.Placeholder == nil {
+		return "?"
+	}
+	return o.Placeholder(n)
+}
+
+// defaultOutboxBatch is the number of entries pushed in one request when
+// OutboxRelay.BatchSize is not set.
+const defaultOutboxBatch = 100
+
+// OutboxRelay pushes KPIs from an OutboxStore and marks them as sent. An entry
+// is marked only after a successful push; if marking fails, the entry is
+// pushed again. The pushes use ensure_unique, so an entry pushed again isn't
+// counted twice.
+type OutboxRelay struct {
+	Pusher Pusher
+	Store  OutboxStore
+	// BatchSize is the maximum number of entries pushed in one request. Zero
+	// means 100.
+	BatchSize int
+}
+
+// Relay pushes pending entries until there are none left, and returns the
+// number of entries sent.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	limit := r.BatchSize
+	if limit <= 0 {
+		limit = defaultOutboxBatch
+	}
+
+	var sent int
+	for {
+		entries, err := r.Store.Pending(ctx, limit)
+		if err != nil {
+			return sent, fmt.Errorf("reading outbox: %w", err)
+		}
+		if len(entries) == 0 {
+			return sent, nil
+		}
+
+		var kpis []KPI
+		ids := make([]int64, len(entries))
+		for i, entry := range entries {
+			kpis = append(kpis, entry.KPIs...)
+			ids[i] = entry.ID
+		}
+		if len(kpis) > 0 {
+			if _, err := r.Pusher.InsertAll(ctx, kpis, true); err != nil {
+				return sent, fmt.Errorf("pushing %d outbox entries: %w", len(entries), err)
+			}
+		}
+		if err := r.Store.MarkSent(ctx, ids); err != nil {
+			return sent, fmt.Errorf("marking outbox entries sent: %w", err)
+		}
+		sent += len(entries)
+		if len(entries) < limit {
+			return sent, nil
+		}
+	}
+}
+
+// RelayEvery runs Relay immediately and then every interval. It blocks until
+// ctx is cancelled and returns the context's error. Errors of individual
+// rounds are passed to onError, which may be nil.
+func (r *OutboxRelay) RelayEvery(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Relay(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package databox
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestSQLOutbox(t *testing.T) {
+	t.Parallel()
+
+	stored := `[{"key":"orders","value":1,"attributes":{"shop":"eshop"}},{"metrics":{"a":2},"date":"2020-01-01"}]`
+	db := openFakeDB(t, map[string]fakeRows{
+		"SELECT id, kpis FROM outbox WHERE sent_at IS NULL ORDER BY id LIMIT 10": {
+			columns: []string{"id", "kpis"},
+			values:  [][]driver.Value{{int64(7), stored}},
+		},
+	})
+	outbox := &SQLOutbox{DB: db, Table: "outbox", Placeholder: func(n int) string { return "$" + strconv.Itoa(n) }}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.SaveToOutbox(context.Background(), tx, []KPI{{Key: "orders", Value: 1}}); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := outbox.Pending(context.Background(), 10)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(entries) != 1 || entries[0].ID != 7 || len(entries[0].KPIs) != 2 || entries[0].KPIs[0].Attributes["shop"] != "eshop" || entries[0].KPIs[1].Metrics["a"] != 2 {
+		t.Error("Unexpected entries", entries)
+	}
+	if err := outbox.MarkSent(context.Background(), []int64{7, 8}); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+
+	fakeDBsMu.Lock()
+	execs := fakeExecs[t.Name()]
+	fakeDBsMu.Unlock()
+	if len(execs) != 2 {
+		t.Fatal("Unexpected statements", execs)
+	}
+	if execs[0].query != "INSERT INTO outbox (kpis) VALUES ($1)" || execs[0].args[0] != `[{"key":"orders","value":1}]` {
+		t.Error("Unexpected insert", execs[0])
+	}
+	if execs[1].query != "UPDATE outbox SET sent_at = $1 WHERE id IN ($2, $3)" || execs[1].args[1] != int64(7) {
+		t.Error("Unexpected update", execs[1])
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	statusCode := 500
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(statusCode, `{"id":"someRandomId"}`), nil
+	})
+
+	store := &memoryOutbox{}
+	for i := 0; i < 5; i++ {
+		store.entries = append(store.entries, OutboxEntry{ID: int64(i), KPIs: []KPI{{Key: "orders", Value: float32(i)}}})
+	}
+	relay := &OutboxRelay{Pusher: client, Store: store, BatchSize: 2}
+
+	if sent, err := relay.Relay(context.Background()); err == nil || sent != 0 || len(store.sent) != 0 {
+		t.Error("Failed push must not mark entries", sent, err)
+	}
+	statusCode = 200
+	sent, err := relay.Relay(context.Background())
+	if err != nil || sent != 5 || len(store.sent) != 5 {
+		t.Error("All entries must be relayed", sent, err)
+	}
+	if len(requests) != 4 || len(requests[1].Data) != 2 || len(requests[3].Data) != 1 {
+		t.Error("Entries must be pushed in batches", requests)
+	}
+
+	store.entries = append(store.entries, OutboxEntry{ID: 5, KPIs: []KPI{{Key: "orders", Value: 5, Date: "2020-01-01"}}})
+	store.failMark = true
+	if sent, err := relay.Relay(context.Background()); err == nil || sent != 0 {
+		t.Error("Failed marking must be reported", sent, err)
+	}
+	store.failMark = false
+	if sent, err := relay.Relay(context.Background()); err != nil || sent != 1 {
+		t.Error("Unmarked entry must be relayed again", sent, err)
+	}
+	for _, request := range requests[4:] {
+		if len(request.Data) != 1 || request.Meta["ensure_unique"] != true {
+			t.Error("Entries must be pushed with ensure_unique", request)
+		}
+	}
+	if len(requests) != 6 {
+		t.Error("Unexpected requests", requests)
+	}
+}
+
+// memoryOutbox is an in-memory OutboxStore.
+type memoryOutbox struct {
+	entries []OutboxEntry
+	sent    map[int64]bool
+	// failMark makes MarkSent fail.
+	failMark bool
+}
+
+func (o *memoryOutbox) Pending(_ context.Context, limit int) ([]OutboxEntry, error) {
+	var pending []OutboxEntry
+	for _, entry := range o.entries {
+		if !o.sent[entry.ID] && len(pending) < limit {
+			pending = append(pending, entry)
+		}
+	}
+	return pending, nil
+}
+
+func (o *memoryOutbox) MarkSent(_ context.Context, ids []int64) error {
+	if o.failMark {
+		return errors.New("database is down")
+	}
+	if o.sent == nil {
+		o.sent = make(map[int64]bool)
+	}
+	for _, id := range ids {
+		if o.sent[id] {
+			return errors.New("marked twice")
+		}
+		o.sent[id] = true
+	}
+	return nil
+}
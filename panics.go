@@ -0,0 +1,157 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is returned when user-supplied code, such as a collector, a
+// transform or a hook, panics. The panic is recovered, so one buggy
+// collector fails its round instead of crashing the process, and the error
+// reaches the caller or the onError hook of the loop running it.
+type PanicError struct {
+	// Hook names the panicking code, e.g. "collector" or "transform".
+	Hook string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Hook, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic turns a panic into *PanicError stored in err. It must be
+// deferred directly:
+//
+//	defer recoverPanic("collector", &err)
+func recoverPanic(hook string, err *error) {
+	if value := recover(); value != nil {
+		*err = &PanicError{Hook: hook, Value: value, Stack: debug.Stack()}
+	}
+}
+
+// runCollector calls collector.Collect, recovering from panics.
+func runCollector(ctx context.Context, collector Collector) (kpis []KPI, err error) {
+	defer recoverPanic("collector", &err)
+	return collector.Collect(ctx)
+}
+
+// runTransforms applies transforms in order, recovering from panics.
+func runTransforms(ctx context.Context, kpis []KPI, transforms []Transform) (_ []KPI, err error) {
+	defer recoverPanic("transform", &err)
+	return ChainTransforms(transforms...)(ctx, kpis)
+}
+
+// sequence calls c.Sequence, recovering from panics.
+func (c *Client) sequence(ctx context.Context) (_ uint64, err error) {
+	defer recoverPanic("Sequence", &err)
+	return c.Sequence(ctx)
+}
+
+// signRequest calls c.SignRequest, recovering from panics.
+func (c *Client) signRequest(request *http.Request) (err error) {
+	defer recoverPanic("SignRequest", &err)
+	return c.SignRequest(request)
+}
+
+// matchRoute calls route.Match, recovering from panics.
+func matchRoute(route Route, kpi KPI) (_ bool, err error) {
+	defer recoverPanic("Match", &err)
+	return route.Match(kpi), nil
+}
+
+// reportAnomaly calls g.OnAnomaly, recovering from panics.
+func (g *AnomalyGuard) reportAnomaly(anomaly Anomaly) (err error) {
+	defer recoverPanic("OnAnomaly", &err)
+	g.OnAnomaly(anomaly)
+	return nil
+}
+
+// reportDuplicate calls d.OnDuplicate, recovering from panics.
+func (d *DedupCache) reportDuplicate(point DataPoint) (err error) {
+	defer recoverPanic("OnDuplicate", &err)
+	d.OnDuplicate(point)
+	return nil
+}
+
+// reportProgress calls r.Progress, recovering from panics.
+func (r *Replayer) reportProgress(progress ReplayProgress) (err error) {
+	defer recoverPanic("Progress", &err)
+	r.Progress(progress)
+	return nil
+}
+
+// loadCheckpoint calls r.Checkpoint.Load, recovering from panics.
+func (r *Replayer) loadCheckpoint() (_ ReplayPosition, err error) {
+	defer recoverPanic("Checkpoint", &err)
+	return r.Checkpoint.Load()
+}
+
+// saveCheckpoint calls r.Checkpoint.Save, recovering from panics.
+func (r *Replayer) saveCheckpoint(position ReplayPosition) (err error) {
+	defer recoverPanic("Checkpoint", &err)
+	return r.Checkpoint.Save(position)
+}
+
+// reportCompaction calls c.OnCompaction, recovering from panics.
+func (c *Client) reportCompaction(report CompactionReport) (err error) {
+	defer recoverPanic("OnCompaction", &err)
+	c.OnCompaction(report)
+	return nil
+}
+
+// check calls p.Check, recovering from panics.
+func (p *NamingPolicy) check(key string) (err error) {
+	defer recoverPanic("Check", &err)
+	return p.Check(key)
+}
+
+// rewriteKey calls rewrite, p.Rewrite or SnakeCase, recovering from panics.
+func rewriteKey(rewrite func(key string) string, key string) (_ string, err error) {
+	defer recoverPanic("Rewrite", &err)
+	return rewrite(key), nil
+}
+
+// emit calls t.sink, recovering from panics.
+func (t *tracer) emit(event TraceEvent) (err error) {
+	defer recoverPanic("trace sink", &err)
+	t.sink(event)
+	return nil
+}
+
+// backfillValues calls values, recovering from panics.
+func backfillValues(ctx context.Context, values func(ctx context.Context, t time.Time) ([]KPI, error), t time.Time) (_ []KPI, err error) {
+	defer recoverPanic("values", &err)
+	return values(ctx, t)
+}
+
+// reportProgress calls b.Progress, recovering from panics.
+func (b *Backfiller) reportProgress(progress BackfillProgress) (err error) {
+	defer recoverPanic("Progress", &err)
+	b.Progress(progress)
+	return nil
+}
+
+// attributes calls t.Attributes, recovering from panics.
+func (t *Tenants) attributes(tenant string) (_ map[string]interface{}, err error) {
+	defer recoverPanic("Attributes", &err)
+	return t.Attributes(tenant), nil
+}
+
+// reportError calls s.OnError, recovering from panics.
+func (s *CollectorSet) reportError(name string, collectorErr error) (err error) {
+	defer recoverPanic("OnError", &err)
+	s.OnError(name, collectorErr)
+	return nil
+}
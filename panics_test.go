@@ -0,0 +1,172 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var errs []error
+	_ = client.CollectEvery(ctx, time.Millisecond, CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+		panic("boom")
+	}), func(err error) {
+		errs = append(errs, err)
+		if len(errs) == 2 {
+			cancel()
+		}
+	})
+	var panicErr *PanicError
+	if len(errs) != 2 || !errors.As(errs[0], &panicErr) || panicErr.Hook != "collector" || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatal("Collector panics must be reported and the loop must go on", errs)
+	}
+
+	client.Transforms = []Transform{func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		panic(io.ErrUnexpectedEOF)
+	}}
+	_, err := client.InsertAll(context.Background(), []KPI{{Key: "a", Value: 1}}, false)
+	if !errors.As(err, &panicErr) || panicErr.Hook != "transform" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("Expected transform panic", err)
+	}
+
+	client.Transforms = nil
+	client.Sequence = func(ctx context.Context) (uint64, error) {
+		var sequence map[string]uint64
+		sequence["next"]++
+		return sequence["next"], nil
+	}
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "a", Value: 1}}, false); !errors.As(err, &panicErr) || panicErr.Hook != "Sequence" {
+		t.Error("Expected Sequence panic", err)
+	}
+}
+
+type panickingCheckpoint struct{}
+
+func (panickingCheckpoint) Load() (ReplayPosition, error) { return ReplayPosition{}, nil }
+func (panickingCheckpoint) Save(ReplayPosition) error     { panic("boom") }
+
+func TestHookPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	newClient := func() *Client {
+		client := NewClient(getToken())
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return jsonResponse(200, `{"id":"someRandomId"}`), nil
+		})
+		return client
+	}
+	boom := func() { panic("boom") }
+	kpis := []KPI{{Key: "a", Value: 1, Date: "2020-01-01"}}
+	hooks := []struct {
+		hook string
+		run  func() error
+	}{
+		{"transform", func() error {
+			router := &Router{Default: newClient(), Transforms: []Transform{func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+				boom()
+				return kpis, nil
+			}}}
+			_, err := router.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"Match", func() error {
+			router := &Router{Routes: []Route{{Match: func(KPI) bool { boom(); return true }, Target: newClient()}}}
+			_, err := router.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"OnAnomaly", func() error {
+			client := newClient()
+			client.AnomalyGuard = &AnomalyGuard{
+				Rules:     map[string]AnomalyRule{"a": {Range: &ValueRange{Min: 10, Max: 20}}},
+				OnAnomaly: func(Anomaly) { boom() },
+			}
+			_, err := client.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"OnDuplicate", func() error {
+			client := newClient()
+			client.DedupCache = &DedupCache{Window: time.Hour, Drop: true, OnDuplicate: func(DataPoint) { boom() }}
+			if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+				return err
+			}
+			_, err := client.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"Progress", func() error {
+			replayer := &Replayer{Client: newClient(), Progress: func(ReplayProgress) { boom() }}
+			return replayer.ReplayDir(context.Background(), fstest.MapFS{"a.json": {Data: []byte(`{"data":[{"$a":1}]}`)}}, "*.json")
+		}},
+		{"Checkpoint", func() error {
+			replayer := &Replayer{Client: newClient(), Checkpoint: panickingCheckpoint{}}
+			return replayer.ReplayDir(context.Background(), fstest.MapFS{"a.json": {Data: []byte(`{"data":[{"$a":1}]}`)}}, "*.json")
+		}},
+		{"Check", func() error {
+			client := newClient()
+			client.NamingPolicy = &NamingPolicy{Mode: NamingWarn, Check: func(string) error { boom(); return nil }}
+			_, err := client.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"Rewrite", func() error {
+			client := newClient()
+			client.NamingPolicy = &NamingPolicy{
+				Mode:    NamingRewrite,
+				Pattern: regexp.MustCompile(`^b$`),
+				Rewrite: func(string) string { boom(); return "b" },
+			}
+			_, err := client.InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"values", func() error {
+			backfiller := &Backfiller{Client: newClient()}
+			return backfiller.Backfill(context.Background(), time.Now(), time.Now(), time.Hour, func(context.Context, time.Time) ([]KPI, error) {
+				boom()
+				return kpis, nil
+			})
+		}},
+		{"Progress", func() error {
+			backfiller := &Backfiller{Client: newClient(), Progress: func(BackfillProgress) { boom() }}
+			return backfiller.Backfill(context.Background(), time.Now(), time.Now(), time.Hour, func(context.Context, time.Time) ([]KPI, error) {
+				return kpis, nil
+			})
+		}},
+		{"Attributes", func() error {
+			tenants := &Tenants{Client: newClient(), Attributes: func(string) map[string]interface{} { boom(); return nil }}
+			_, err := tenants.Pusher("acme").InsertAll(context.Background(), kpis, false)
+			return err
+		}},
+		{"OnError", func() error {
+			collectors := &CollectorSet{OnError: func(string, error) { boom() }}
+			collectors.Set("failing", CollectorFunc(func(context.Context) ([]KPI, error) {
+				return nil, io.ErrUnexpectedEOF
+			}))
+			_, err := collectors.Collect(context.Background())
+			return err
+		}},
+	}
+	for _, test := range hooks {
+		var panicErr *PanicError
+		if err := test.run(); !errors.As(err, &panicErr) || panicErr.Hook != test.hook {
+			t.Errorf("Expected %s panic, got %v", test.hook, err)
+		}
+	}
+
+	client := newClient()
+	ctx := WithTrace(context.Background(), func(TraceEvent) { boom() })
+	if _, err := client.InsertAll(ctx, kpis, false); err != nil {
+		t.Error("Panicking trace sink must not fail the push", err)
+	}
+}
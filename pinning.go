@@ -0,0 +1,30 @@
+package databox
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// PinError is returned when the certificate chain of the server doesn't
+// contain any of the pinned public keys, e.g. because the TLS connection is
+// intercepted.
+type PinError struct {
+	// Host is the server name of the connection.
+	Host string
+	// Pins are the SPKI pins of the certificates presented by the server.
+	Pins []string
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("certificate of %s doesn't match any pinned public key", e.Host)
+}
+
+// SPKIPin returns the pin of the certificate's public key: base64 encoded
+// SHA-256 hash of its SubjectPublicKeyInfo, as used by HPKP and
+// `openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64`.
+func SPKIPin(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
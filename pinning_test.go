@@ -0,0 +1,80 @@
+//go:build !js && !tinygo
+
+package databox
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPinSPKI(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"someRandomId"}`))
+	}))
+	defer server.Close()
+
+	newClient := func(pins ...string) *Client {
+		client := NewClient(getToken())
+		client.PushHost = server.URL
+		client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = x509.NewCertPool()
+		client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.RootCAs.AddCert(server.Certificate())
+		if err := client.PinSPKI(pins...); err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	if _, err := newClient(SPKIPin(server.Certificate())).Push(&KPI{Key: "a", Value: 1}); err != nil {
+		t.Error("Must be nil", err)
+	}
+
+	var pinErr *PinError
+	_, err := newClient("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=").Push(&KPI{Key: "a", Value: 1})
+	if !errors.As(err, &pinErr) {
+		t.Fatal("Expected PinError", err)
+	}
+	if len(pinErr.Pins) == 0 || pinErr.Pins[0] != SPKIPin(server.Certificate()) {
+		t.Error("Presented pins must be reported", pinErr.Pins)
+	}
+
+	// A pinned key in a certificate outside the verified chains must not
+	// satisfy the pin.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "extra"}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := newClient(SPKIPin(extra)).HTTPClient.Transport.(*http.Transport).TLSClientConfig.VerifyConnection
+	err = verify(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{server.Certificate(), extra},
+		VerifiedChains:   [][]*x509.Certificate{{server.Certificate()}},
+	})
+	if !errors.As(err, &pinErr) {
+		t.Error("Unverified certificate must not match", err)
+	}
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(nil)
+	if err := client.PinSPKI("pin"); err == nil {
+		t.Error("Custom transport must be refused")
+	}
+}
@@ -0,0 +1,135 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transform rewrites KPIs before they are pushed. It can modify, enrich,
+// split or drop KPIs by returning a different slice; e.g. compute ratios or
+// convert units. An error aborts the push. Transforms must not modify the
+// KPIs they receive, they return modified copies instead.
+type Transform func(ctx context.Context, kpis []KPI) ([]KPI, error)
+
+// TransformEach returns a Transform applying f to every KPI. The f returns
+// the KPIs replacing its input: none to drop it, or several to split it.
+func TransformEach(f func(kpi KPI) ([]KPI, error)) Transform {
+	return func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		transformed := make([]KPI, 0, len(kpis))
+		for _, kpi := range kpis {
+			result, err := f(kpi)
+			if err != nil {
+				return nil, err
+			}
+			transformed = append(transformed, result...)
+		}
+		return transformed, nil
+	}
+}
+
+// ChainTransforms composes transforms into one, applied in order.
+func ChainTransforms(transforms ...Transform) Transform {
+	return func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		var err error
+		for _, transform := range transforms {
+			if kpis, err = transform(ctx, kpis); err != nil {
+				return nil, err
+			}
+		}
+		return kpis, nil
+	}
+}
+
+// prepare runs the KPIs through the client's pipeline: transforms, naming
+// policy, schema and value checks, deduplication and downsampling. The returned commit
+// function is meant to be called after a successful push.
+func (c *Client) prepare(ctx context.Context, kpis []KPI) ([]KPI, func(), error) {
+	commit := func() {}
+	kpis = withContextAttributes(ctx, kpis)
+	trace(ctx, "input", kpis, "", nil)
+	var report *compaction
+	if c.OnCompaction != nil {
+		report = newCompaction(kpis)
+	}
+	for i := range c.Transforms {
+		var err error
+		kpis, err = runTransforms(ctx, kpis, c.Transforms[i:i+1])
+		trace(ctx, fmt.Sprintf("transform %d", i), kpis, "", err)
+		if err != nil {
+			return nil, commit, err
+		}
+	}
+	if report != nil && len(c.Transforms) > 0 {
+		report.stage("transforms", kpis)
+	}
+	kpis, err := c.applyNamingPolicy(kpis)
+	if c.NamingPolicy != nil {
+		trace(ctx, "naming", kpis, "", err)
+	}
+	if err != nil {
+		return nil, commit, err
+	}
+	commitChecks, err := c.check(kpis)
+	if err != nil {
+		trace(ctx, "validation", nil, "", err)
+		return nil, commit, err
+	}
+	commit = chainCommits(commit, commitChecks)
+	if c.DedupCache != nil {
+		var commitDedup func()
+		kpis, commitDedup, err = c.DedupCache.filter(kpis)
+		trace(ctx, "dedup", kpis, "", err)
+		if err != nil {
+			return nil, commit, err
+		}
+		commit = chainCommits(commit, commitDedup)
+		if report != nil {
+			report.stage("dedup", kpis)
+		}
+	}
+	if c.Downsampler != nil {
+		var commitSamples func()
+		kpis, commitSamples = c.Downsampler.filter(kpis)
+		trace(ctx, "downsample", kpis, "", nil)
+		commit = chainCommits(commit, commitSamples)
+		if report != nil {
+			report.stage("downsample", kpis)
+		}
+	}
+	if report != nil {
+		if err := c.reportCompaction(report.done(kpis)); err != nil {
+			return nil, commit, err
+		}
+	}
+	return kpis, commit, nil
+}
+
+// check validates kpis against the schema and the anomaly guard, and warns
+// about changed units. The returned commit function is meant to be called
+// after a successful push.
+func (c *Client) check(kpis []KPI) (func(), error) {
+	commit := func() {}
+	if err := c.validateSchema(kpis); err != nil {
+		return commit, err
+	}
+	if c.AnomalyGuard != nil {
+		commitValues, err := c.AnomalyGuard.check(kpis)
+		if err != nil {
+			return commit, err
+		}
+		commit = commitValues
+	}
+	if c.UnitChecker != nil {
+		commit = chainCommits(commit, c.UnitChecker.check(kpis, c.Logger))
+	}
+	return commit, nil
+}
+
+// chainCommits returns a commit function calling all commits.
+func chainCommits(commits ...func()) func() {
+	return func() {
+		for _, commit := range commits {
+			commit()
+		}
+	}
+}
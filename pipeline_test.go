@@ -0,0 +1,78 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	dropDebug := TransformEach(func(kpi KPI) ([]KPI, error) {
+		if strings.HasPrefix(kpi.Key, "debug.") {
+			return nil, nil
+		}
+		return []KPI{kpi}, nil
+	})
+	centsToDollars := TransformEach(func(kpi KPI) ([]KPI, error) {
+		if kpi.Unit == "cents" {
+			kpi.Value, kpi.Unit = kpi.Value/100, "USD"
+		}
+		return []KPI{kpi}, nil
+	})
+	ratio := func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		values := map[string]float32{}
+		for _, kpi := range kpis {
+			values[kpi.Key] = kpi.Value
+		}
+		if values["visits"] > 0 {
+			kpis = append(kpis, KPI{Key: "conversion", Value: values["orders"] / values["visits"]})
+		}
+		return kpis, nil
+	}
+	client.Transforms = []Transform{dropDebug, ChainTransforms(centsToDollars, ratio)}
+
+	kpis := []KPI{
+		{Key: "debug.gc", Value: 1},
+		{Key: "revenue", Value: 1250, Unit: "cents"},
+		{Key: "orders", Value: 5},
+		{Key: "visits", Value: 100},
+	}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	data := requests[0].Data
+	if len(data) != 4 || data[0]["$revenue"] != 12.5 || data[0]["unit"] != "USD" || data[3]["$conversion"] != 0.05 {
+		t.Error("Unexpected data", data)
+	}
+	if kpis[1].Value != 1250 {
+		t.Error("KPI must not be modified")
+	}
+
+	if status, err := client.Push(&KPI{Key: "debug.gc", Value: 1}); err != nil || status == nil || len(requests) != 1 {
+		t.Error("Dropped KPIs must not be sent", status, err)
+	}
+
+	failure := errors.New("transform failed")
+	client.Transforms = append(client.Transforms, func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		return nil, failure
+	})
+	if _, err := client.Push(&KPI{Key: "orders", Value: 1}); !errors.Is(err, failure) {
+		t.Error("Transform error must abort the push", err)
+	}
+}
@@ -0,0 +1,143 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlanAction tells what pushing a KPI would change.
+type PlanAction int
+
+const (
+	// PlanUnchanged means all values of the KPI were already pushed.
+	PlanUnchanged PlanAction = iota
+	// PlanCreate means some data point of the KPI isn't in the history.
+	PlanCreate
+	// PlanUpdate means some data point of the KPI was pushed with a
+	// different value.
+	PlanUpdate
+)
+
+func (a PlanAction) String() string {
+	switch a {
+	case PlanCreate:
+		return "create"
+	case PlanUpdate:
+		return "update"
+	}
+	return "unchanged"
+}
+
+// PlanItem is a KPI with the outcome of pushing it.
+type PlanItem struct {
+	KPI    KPI
+	Action PlanAction
+	// Previous holds the previously pushed values by metric key, for the
+	// data points found in the history.
+	Previous map[string]float64
+}
+
+// Plan compares KPIs to the push history before they are pushed. It allows
+// cautious corrections of historical metrics: review the plan, then Apply it.
+type Plan struct {
+	Items []PlanItem
+}
+
+// Changes returns the KPIs that would be created or updated.
+func (p *Plan) Changes() []KPI {
+	var kpis []KPI
+	for _, item := range p.Items {
+		if item.Action != PlanUnchanged {
+			kpis = append(kpis, item.KPI)
+		}
+	}
+	return kpis
+}
+
+// String renders the plan, one data point per line, prefixed by "+" for
+// creations, "~" for updates and " " for unchanged values.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, item := range p.Items {
+		values := kpiValues(item.KPI)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := values[key]
+			previous, ok := item.Previous[key]
+			switch {
+			case !ok:
+				fmt.Fprintf(&b, "+ %s %s: %v\n", key, item.KPI.Date, value)
+			case float32(previous) != value:
+				fmt.Fprintf(&b, "~ %s %s: %v -> %v\n", key, item.KPI.Date, previous, value)
+			default:
+				fmt.Fprintf(&b, "  %s %s: %v\n", key, item.KPI.Date, value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Plan compares kpis to the last history pushes and returns what pushing them
+// would change. Data points are matched by metric key, date and attributes.
+// Pushes rejected with errors are skipped.
+func (c *Client) Plan(ctx context.Context, kpis []KPI, history int) (*Plan, error) {
+	pushes, err := c.LastPushesCtx(ctx, history)
+	if err != nil {
+		return nil, fmt.Errorf("fetching push history: %w", err)
+	}
+
+	// The history is ordered from the latest push, the first value wins.
+	pushed := make(map[string]float64)
+	for _, point := range historyPoints(acceptedPushes(pushes)) {
+		id := pointID(point.key, point.date, point.attributes)
+		if _, ok := pushed[id]; !ok {
+			pushed[id] = point.value
+		}
+	}
+
+	plan := &Plan{Items: make([]PlanItem, 0, len(kpis))}
+	for _, kpi := range kpis {
+		item := PlanItem{KPI: kpi, Previous: make(map[string]float64)}
+		for key, value := range kpiValues(kpi) {
+			previous, ok := pushed[pointID(key, kpi.Date, kpi.Attributes)]
+			switch {
+			case !ok:
+				item.Action = PlanCreate
+				continue
+			case float32(previous) != value && item.Action == PlanUnchanged:
+				item.Action = PlanUpdate
+			}
+			item.Previous[key] = previous
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// Apply pushes the KPIs the plan would create or update. Nothing is sent when
+// there are no changes.
+func (c *Client) Apply(ctx context.Context, plan *Plan) (*ResponseStatus, error) {
+	changes := plan.Changes()
+	if len(changes) == 0 {
+		return &ResponseStatus{}, nil
+	}
+	return c.InsertAll(ctx, changes, false)
+}
+
+// kpiValues returns values of kpi by metric key.
+func kpiValues(kpi KPI) map[string]float32 {
+	values := make(map[string]float32, len(kpi.Metrics)+1)
+	if kpi.Key != "" {
+		values[kpi.Key] = kpi.Value
+	}
+	for key, value := range kpi.Metrics {
+		values[key] = value
+	}
+	return values
+}
@@ -0,0 +1,62 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestPlanApply(t *testing.T) {
+	t.Parallel()
+
+	var pushed []KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return jsonResponse(200, `[
+				{"request":{"body":{"data":[{"$revenue":125,"date":"2020-01-02","region":"eu"},{"$revenue":80,"date":"2020-01-03","region":"eu"}]},"errors":["invalid value"]}},
+				{"request":{"body":{"data":[{"$revenue":120,"date":"2020-01-02","region":"eu"}]}}},
+				{"request":{"body":{"data":[{"$revenue":100,"date":"2020-01-02","region":"eu"},{"$revenue":90,"date":"2020-01-01","region":"eu"}]}}}
+			]`), nil
+		}
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		pushed = append(pushed, wrap)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	eu := map[string]interface{}{"region": "eu"}
+	kpis := []KPI{
+		{Key: "revenue", Value: 90, Date: "2020-01-01", Attributes: eu},
+		{Key: "revenue", Value: 125, Date: "2020-01-02", Attributes: eu},
+		{Key: "revenue", Value: 80, Date: "2020-01-03", Attributes: eu},
+		{Key: "revenue", Value: 60, Date: "2020-01-01", Attributes: map[string]interface{}{"region": "us"}},
+	}
+	plan, err := client.Plan(context.Background(), kpis, 10)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	actions := []PlanAction{PlanUnchanged, PlanUpdate, PlanCreate, PlanCreate}
+	for i, item := range plan.Items {
+		if item.Action != actions[i] {
+			t.Errorf("item %d: got %v, want %v", i, item.Action, actions[i])
+		}
+	}
+	if plan.Items[1].Previous["revenue"] != 120 {
+		t.Error("Latest pushed value must be used", plan.Items[1].Previous)
+	}
+	want := "  revenue 2020-01-01: 90\n~ revenue 2020-01-02: 120 -> 125\n+ revenue 2020-01-03: 80\n+ revenue 2020-01-01: 60\n"
+	if plan.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", plan, want)
+	}
+
+	if _, err := client.Apply(context.Background(), plan); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(pushed) != 1 || len(pushed[0].Data) != 3 {
+		t.Error("Only changes must be pushed", pushed)
+	}
+}
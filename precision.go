@@ -0,0 +1,46 @@
+package databox
+
+import "math"
+
+// roundKPIs returns kpis with values rounded according to precision. The
+// KPIs are copied, the caller's ones are left untouched.
+func roundKPIs(kpis []KPI, precision map[string]int) []KPI {
+	if len(precision) == 0 {
+		return kpis
+	}
+
+	decimals := func(key string) (int, bool) {
+		if d, ok := precision[key]; ok {
+			return d, true
+		}
+		d, ok := precision[""]
+		return d, ok
+	}
+
+	rounded := make([]KPI, len(kpis))
+	for i, kpi := range kpis {
+		if kpi.Key != "" {
+			if d, ok := decimals(kpi.Key); ok {
+				kpi.Value = round(kpi.Value, d)
+			}
+		}
+		if len(kpi.Metrics) > 0 {
+			metrics := make(map[string]float32, len(kpi.Metrics))
+			for key, value := range kpi.Metrics {
+				if d, ok := decimals(key); ok {
+					value = round(value, d)
+				}
+				metrics[key] = value
+			}
+			kpi.Metrics = metrics
+		}
+		rounded[i] = kpi
+	}
+	return rounded
+}
+
+// round rounds value half away from zero to the decimals.
+func round(value float32, decimals int) float32 {
+	scale := math.Pow10(decimals)
+	return float32(math.Round(exactFloat64(value)*scale) / scale)
+}
@@ -0,0 +1,47 @@
+package databox
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrecision(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	client.Precision = map[string]int{"revenue": 2, "": 0, "visitors": -2}
+
+	kpis := []KPI{
+		{Key: "revenue", Value: 19.98765},
+		{Key: "orders", Value: 2.5},
+		{Metrics: map[string]float32{"visitors": 1249, "revenue": 0.125}},
+	}
+	if _, err := client.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"data":[{"$revenue":19.99},{"$orders":3},{"$visitors":1200,"$revenue":0.13}]}`
+	if len(body) != len(want) || !contains(body, `"$revenue":19.99`, `"$orders":3`, `"$visitors":1200`, `"$revenue":0.13`) {
+		t.Errorf("got %s, want %s", body, want)
+	}
+	if kpis[0].Value != 19.98765 || kpis[2].Metrics["visitors"] != 1249 {
+		t.Error("KPIs of the caller must not be modified")
+	}
+}
+
+func contains(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if !strings.Contains(s, substring) {
+			return false
+		}
+	}
+	return true
+}
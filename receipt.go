@@ -0,0 +1,79 @@
+package databox
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Receipt records the outcome of a push request.
+type Receipt struct {
+	Time time.Time `json:"time"`
+	// Keys are the metric keys of the pushed KPIs.
+	Keys []string `json:"keys"`
+	// PushID is the response ID of a successful push.
+	PushID string `json:"push_id,omitempty"`
+	// Status is "ok" or "error".
+	Status string `json:"status"`
+	// Error describes the failure.
+	Error string `json:"error,omitempty"`
+}
+
+// ReceiptLog writes a Receipt per push request as JSON Lines, so pushes can
+// be reconciled later without a database. ReceiptLog is safe for concurrent
+// use.
+type ReceiptLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewReceiptLog returns a log writing receipts to w.
+func NewReceiptLog(w io.Writer) *ReceiptLog {
+	return &ReceiptLog{w: w}
+}
+
+// OpenReceiptFile opens, or creates, the file at path for appending receipts.
+// The caller is responsible for closing the returned file.
+func OpenReceiptFile(path string) (*ReceiptLog, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewReceiptLog(f), f, nil
+}
+
+// Write appends the receipt to the log.
+func (l *ReceiptLog) Write(receipt Receipt) error {
+	line, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(line, '\n'))
+	return err
+}
+
+// record writes the receipt of a push. Failure to write the receipt doesn't
+// fail the push, it's only logged.
+func (l *ReceiptLog) record(kpis []KPI, responseStatus *ResponseStatus, pushErr error, logger Logger) {
+	receipt := Receipt{Time: time.Now().UTC(), Status: "ok"}
+	for _, kpi := range kpis {
+		for key := range kpiValues(kpi) {
+			receipt.Keys = append(receipt.Keys, key)
+		}
+	}
+	sort.Strings(receipt.Keys)
+	if pushErr != nil {
+		receipt.Status, receipt.Error = "error", pushErr.Error()
+	} else {
+		receipt.PushID = responseStatus.ID
+	}
+
+	if err := l.Write(receipt); err != nil && logger != nil {
+		logger.Printf("databox: writing receipt: %v", err)
+	}
+}
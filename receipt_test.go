@@ -0,0 +1,60 @@
+package databox
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReceipts(t *testing.T) {
+	t.Parallel()
+
+	statusCode := 200
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(statusCode, `{"id":"someRandomId","type":"invalid_json","message":"some error message"}`), nil
+	})
+
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	receipts, f, err := OpenReceiptFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Receipts = receipts
+
+	_, _ = client.Push(&KPI{Key: "orders", Metrics: map[string]float32{"revenue": 10}})
+	statusCode = 400
+	_, _ = client.Push(&KPI{Key: "orders", Value: 1})
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []Receipt
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var receipt Receipt
+		if err := json.Unmarshal(scanner.Bytes(), &receipt); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, receipt)
+	}
+
+	if len(lines) != 2 {
+		t.Fatal("Expected 2 receipts, got", lines)
+	}
+	if !reflect.DeepEqual(lines[0].Keys, []string{"orders", "revenue"}) || lines[0].PushID != "someRandomId" || lines[0].Status != "ok" {
+		t.Error("Unexpected receipt", lines[0])
+	}
+	if lines[1].Status != "error" || lines[1].Error == "" || lines[1].PushID != "" || lines[1].Time.IsZero() {
+		t.Error("Unexpected receipt", lines[1])
+	}
+}
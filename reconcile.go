@@ -0,0 +1,72 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DataPoint is a single value of a metric.
+type DataPoint struct {
+	Key        string
+	Value      float32
+	Date       string
+	Unit       string
+	Attributes map[string]interface{}
+}
+
+// Reconcile checks that the expected KPIs were pushed within window, e.g.
+// that yesterday's numbers actually landed. It returns the data points not
+// found in any acknowledged push. A data point matches when its metric key,
+// date, attributes and value are equal. Pushes rejected by Databox don't
+// count.
+func (c *Client) Reconcile(ctx context.Context, expected []KPI, window time.Duration) ([]DataPoint, error) {
+	pushes, err := c.historyWithin(ctx, window)
+	if err != nil {
+		return nil, fmt.Errorf("fetching push history: %w", err)
+	}
+
+	var acknowledged []LastPush
+	for _, push := range pushes {
+		if push.Response.Body.ID != "" && len(push.Request.Errors) == 0 {
+			acknowledged = append(acknowledged, push)
+		}
+	}
+	pushed := make(map[string][]float64)
+	for _, point := range historyPoints(acknowledged) {
+		id := pointID(point.key, point.date, point.attributes)
+		pushed[id] = append(pushed[id], point.value)
+	}
+
+	var missing []DataPoint
+	for _, kpi := range expected {
+		values := kpiValues(kpi)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !containsValue(pushed[pointID(key, kpi.Date, kpi.Attributes)], values[key]) {
+				missing = append(missing, DataPoint{
+					Key:        key,
+					Value:      values[key],
+					Date:       kpi.Date,
+					Unit:       kpi.Unit,
+					Attributes: kpi.Attributes,
+				})
+			}
+		}
+	}
+	return missing, nil
+}
+
+func containsValue(values []float64, value float32) bool {
+	for _, v := range values {
+		if float32(v) == value {
+			return true
+		}
+	}
+	return false
+}
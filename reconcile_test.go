@@ -0,0 +1,43 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	old := time.Now().Add(-72 * time.Hour).UTC().Format(time.RFC3339)
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, fmt.Sprintf(`[
+			{"request":{"date":%q,"body":{"data":[{"$sales":10,"date":"2020-01-01"}]}},"response":{"body":{"id":"a"}}},
+			{"request":{"date":%q,"body":{"data":[{"$sales":11,"date":"2020-01-02"}]},"errors":["invalid"]},"response":{"body":{"id":"b"}}},
+			{"request":{"date":%q,"body":{"data":[{"$sales":12,"date":"2020-01-03"}]}},"response":{"body":{"id":"c"}}}
+		]`, recent, recent, old)), nil
+	})
+
+	missing, err := client.Reconcile(context.Background(), []KPI{
+		{Key: "sales", Value: 10, Date: "2020-01-01"},
+		{Key: "sales", Value: 11, Date: "2020-01-02"},
+		{Key: "sales", Value: 12, Date: "2020-01-03"},
+		{Metrics: map[string]float32{"sales": 9}, Date: "2020-01-01"},
+	}, 24*time.Hour)
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []DataPoint{
+		{Key: "sales", Value: 11, Date: "2020-01-02"},
+		{Key: "sales", Value: 12, Date: "2020-01-03"},
+		{Key: "sales", Value: 9, Date: "2020-01-01"},
+	}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("got %+v, want %+v", missing, want)
+	}
+}
@@ -0,0 +1,87 @@
+package databox
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// redacted replaces secrets in redacted text.
+const redacted = "[REDACTED]"
+
+// Redact replaces the push token of the Client in s, also in the form of a
+// basic auth header, with "[REDACTED]". It's applied to the errors of the
+// Client, use it for custom debug output too.
+func (c *Client) Redact(s string) string {
+	token := c.config().PushToken
+	if token == "" {
+		return s
+	}
+	s = strings.ReplaceAll(s, base64.StdEncoding.EncodeToString([]byte(token+":")), redacted)
+	return strings.ReplaceAll(s, token, redacted)
+}
+
+// String describes the Client without revealing its token.
+func (c *Client) String() string {
+	return fmt.Sprintf("databox.Client{PushHost: %q, PushToken: %s}", c.config().PushHost, redacted)
+}
+
+// GoString implements fmt.GoStringer, so %#v doesn't reveal the token.
+func (c *Client) GoString() string {
+	return c.String()
+}
+
+// String describes the Config without revealing its token.
+func (c Config) String() string {
+	return fmt.Sprintf("databox.Config{PushHost: %q, PushToken: %s}", c.PushHost, redacted)
+}
+
+// GoString implements fmt.GoStringer, so %#v doesn't reveal the token.
+func (c Config) GoString() string {
+	return c.String()
+}
+
+// redactedError replaces an error whose message reveals the push token. It
+// wraps the redacted copies of the original's wrapped errors, so sentinel
+// errors are still matched by errors.Is, but the original isn't reachable.
+type redactedError struct {
+	message string
+	errs    []error
+}
+
+func (e *redactedError) Error() string { return e.message }
+
+func (e *redactedError) Unwrap() []error { return e.errs }
+
+// redactError returns a copy of err and the errors it wraps with the push
+// token redacted from their messages. Errors not revealing the token are
+// kept, and so is the type of *APIError.
+func (c *Client) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if apiErr, ok := err.(*APIError); ok {
+		redacted := *apiErr
+		redacted.Type, redacted.Message = c.Redact(apiErr.Type), c.Redact(apiErr.Message)
+		return &redacted
+	}
+	message := err.Error()
+	redactedMessage := c.Redact(message)
+	if redactedMessage == message {
+		return err
+	}
+	redacted := &redactedError{message: redactedMessage}
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		if wrapped := c.redactError(err.Unwrap()); wrapped != nil {
+			redacted.errs = append(redacted.errs, wrapped)
+		}
+	case interface{ Unwrap() []error }:
+		for _, wrapped := range err.Unwrap() {
+			if wrapped = c.redactError(wrapped); wrapped != nil {
+				redacted.errs = append(redacted.errs, wrapped)
+			}
+		}
+	}
+	return redacted
+}
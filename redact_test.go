@@ -0,0 +1,51 @@
+package databox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	const token = "secret-push-token"
+	client := NewClient(token)
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		// A misbehaving proxy echoing the credentials.
+		return jsonResponse(400, `{"type":"invalid_request","message":"bad auth `+r.Header.Get("Authorization")+` for `+token+`"}`), nil
+	})
+	var receipts bytes.Buffer
+	client.Receipts = NewReceiptLog(&receipts)
+
+	_, pushErr := client.Push(&KPI{Key: "a", Value: 1})
+	_, historyErr := client.LastPushes(1)
+	outputs := map[string]string{
+		"push error":    fmt.Sprint(pushErr),
+		"history error": fmt.Sprint(historyErr),
+		"receipts":      receipts.String(),
+		"client":        fmt.Sprintf("%v %+v %#v %s", client, client, client, client),
+		"config":        fmt.Sprintf("%v %+v %#v", Config{PushToken: token}, Config{PushToken: token}, Config{PushToken: token}),
+	}
+	for name, output := range outputs {
+		if strings.Contains(output, token) || strings.Contains(output, "c2VjcmV0LXB1c2gtdG9rZW46") {
+			t.Errorf("%s reveals the token: %s", name, output)
+		}
+	}
+	if !strings.Contains(outputs["push error"], "[REDACTED]") {
+		t.Error("Token must be replaced", outputs["push error"])
+	}
+	var apiErr *APIError
+	if !errors.As(pushErr, &apiErr) || apiErr.StatusCode != 400 || strings.Contains(apiErr.Message, token) || KindOf(pushErr) != KindValidation {
+		t.Error("Wrapped errors must be redacted too", apiErr)
+	}
+
+	errToken := errors.New("rejected " + token)
+	err := client.redactError(fmt.Errorf("pushing: %w", fmt.Errorf("%w: %w", ErrInvalidKey, errToken)))
+	if !errors.Is(err, ErrInvalidKey) || errors.Is(err, errToken) || strings.Contains(err.Error(), token) {
+		t.Error("Only the sentinel errors must stay reachable", err)
+	}
+}
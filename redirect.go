@@ -0,0 +1,76 @@
+package databox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// RedirectError is returned when the API responds with a redirect the
+// redirect policy refuses to follow. Following redirects could replay the
+// push token to another host.
+type RedirectError struct {
+	// Method is the method of the original request.
+	Method string
+	// StatusCode is the status of the redirect response.
+	StatusCode int
+	// Location is the redirect target.
+	Location string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("refusing to follow %d redirect of %s request to %s", e.StatusCode, e.Method, e.Location)
+}
+
+// SafeRedirects is the default redirect policy of clients created by
+// NewClient. It follows redirects of GET requests within the same host, and
+// refuses the others with *RedirectError.
+func SafeRedirects(request *http.Request, via []*http.Request) error {
+	original := via[0]
+	if original.Method != http.MethodGet || request.URL.Host != original.URL.Host {
+		return newRedirectError(request, via)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// RefuseRedirects is a redirect policy refusing all redirects with
+// *RedirectError.
+func RefuseRedirects(request *http.Request, via []*http.Request) error {
+	return newRedirectError(request, via)
+}
+
+func newRedirectError(request *http.Request, via []*http.Request) error {
+	err := &RedirectError{Method: via[0].Method, Location: request.URL.String()}
+	if request.Response != nil {
+		err.StatusCode = request.Response.StatusCode
+	}
+	return err
+}
+
+// WithRedirectPolicy sets the redirect policy of the HTTP client, e.g.
+// RefuseRedirects. A nil policy follows all redirects like http.Client
+// does by default.
+func WithRedirectPolicy(policy func(request *http.Request, via []*http.Request) error) Option {
+	return func(c *Client) {
+		if c.HTTPClient != nil {
+			c.HTTPClient.CheckRedirect = policy
+		}
+	}
+}
+
+// readResponse reads the response body up to c.MaxResponseBytes.
+func (c *Client) readResponse(response *http.Response) ([]byte, error) {
+	if c.MaxResponseBytes <= 0 {
+		return ioutil.ReadAll(response.Body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, c.MaxResponseBytes+1))
+	if err == nil && int64(len(data)) > c.MaxResponseBytes {
+		return nil, fmt.Errorf("response is larger than %d bytes", c.MaxResponseBytes)
+	}
+	return data, err
+}
@@ -0,0 +1,75 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	t.Parallel()
+
+	var hits int
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer other.Close()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, server.URL+"/target", http.StatusFound)
+		case "/target":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			http.Redirect(w, r, other.URL+r.URL.Path, http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(getToken())
+	client.PushHost = server.URL
+	client.Backoff = ConstantBackoff{MaxRetries: 2}
+
+	var redirectErr *RedirectError
+	_, err := client.Push(&KPI{Key: "a", Value: 1})
+	if !errors.As(err, &redirectErr) || redirectErr.Method != http.MethodPost || redirectErr.StatusCode != http.StatusTemporaryRedirect {
+		t.Error("Expected RedirectError", err)
+	}
+	if _, err := client.LastPushes(1); !errors.As(err, &redirectErr) {
+		t.Error("Cross-host redirect must be refused", err)
+	}
+	if hits != 0 {
+		t.Error("Other host must not be requested", hits)
+	}
+	response, err := client.Do(context.Background(), http.MethodGet, "/moved", nil)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Error("Same-host GET redirect must be followed", err)
+	}
+
+	client = NewClient(getToken(), WithRedirectPolicy(RefuseRedirects))
+	client.PushHost = server.URL
+	if _, err := client.Do(context.Background(), http.MethodGet, "/moved", nil); !errors.As(err, &redirectErr) {
+		t.Error("Expected RedirectError", err)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `[{"request":{"date":"2020-01-01"}}]`), nil
+	})
+	client.MaxResponseBytes = 10
+	if _, err := client.LastPushes(1); err == nil {
+		t.Error("Large response must fail")
+	}
+	client.MaxResponseBytes = 1000
+	if _, err := client.LastPushes(1); err != nil {
+		t.Error("Must be nil", err)
+	}
+}
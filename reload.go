@@ -0,0 +1,63 @@
+package databox
+
+import (
+	"context"
+	"time"
+)
+
+// Config holds the settings of a Client that can be changed while it's in
+// use, see Client.Reload.
+type Config struct {
+	PushToken   string
+	PushHost    string
+	RetryBudget *RetryBudget
+}
+
+// Reload replaces the token, host and retry budget of the Client, e.g. to
+// rotate credentials of a running metric forwarder. Unlike setting the
+// fields, it's safe while the Client is in use; requests started afterwards
+// use the new settings. Limits of a RetryBudget can also be tuned in place
+// with SetLimit.
+func (c *Client) Reload(config Config) {
+	c.reloaded.Store(&config)
+}
+
+// config returns the reloaded settings, or the ones of the fields if the
+// Client was never reloaded.
+func (c *Client) config() Config {
+	if config, ok := c.reloaded.Load().(*Config); ok {
+		return *config
+	}
+	return Config{PushToken: c.PushToken, PushHost: c.PushHost, RetryBudget: c.RetryBudget}
+}
+
+// ReloadEvery calls load immediately and then every interval, and reloads the
+// Client with the returned Config, e.g. read from a watched file or a secret
+// store. It blocks until ctx is cancelled and returns the context's error.
+// Errors of load are passed to onError, which may be nil, and the Client
+// keeps its settings.
+func (c *Client) ReloadEvery(ctx context.Context, interval time.Duration, load func(ctx context.Context) (Config, error), onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if config, err := runLoad(ctx, load); err != nil {
+			if onError != nil {
+				onError(err)
+			}
+		} else {
+			c.Reload(config)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runLoad calls load, recovering from panics.
+func runLoad(ctx context.Context, load func(ctx context.Context) (Config, error)) (config Config, err error) {
+	defer recoverPanic("config loader", &err)
+	return load(ctx)
+}
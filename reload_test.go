@@ -0,0 +1,62 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var tokens []string
+	client := NewClient("old")
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		token, _, _ := r.BasicAuth()
+		mu.Lock()
+		tokens = append(tokens, r.URL.Host+" "+token)
+		mu.Unlock()
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	push := func() {
+		if _, err := client.Push(&KPI{Key: "a", Value: 1}); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+	}
+	push()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	loads := 0
+	load := func(ctx context.Context) (Config, error) {
+		loads++
+		if loads > 1 {
+			cancel()
+			return Config{}, errors.New("unreadable")
+		}
+		return Config{PushToken: "new", PushHost: "https://other.databox.com"}, nil
+	}
+	var errs []error
+	if err := client.ReloadEvery(ctx, time.Millisecond, load, func(err error) { errs = append(errs, err) }); !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled", err)
+	}
+	push()
+
+	if len(errs) != 1 || tokens[0] != "push.databox.com old" || tokens[1] != "other.databox.com new" {
+		t.Error("Reloaded settings must be used", tokens, errs)
+	}
+}
+
+func TestRetryBudgetSetLimit(t *testing.T) {
+	t.Parallel()
+
+	budget := NewRetryBudget(10)
+	budget.SetLimit(1)
+	if !budget.allow() || budget.allow() {
+		t.Error("Lowered limit must apply")
+	}
+}
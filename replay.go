@@ -0,0 +1,212 @@
+package databox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReplayProgress describes the state of a replay after a payload is pushed.
+type ReplayProgress struct {
+	// File is the file being replayed.
+	File string
+	// FileIndex is the index of File among Files, counted from 0.
+	FileIndex int
+	// Files is the number of files matched for the replay.
+	Files int
+	// Payloads is the number of payloads pushed so far.
+	Payloads int
+	// KPIs is the number of data items in payloads pushed so far.
+	KPIs int
+	// Bytes is the size of payloads pushed so far.
+	Bytes int64
+	// ReadBytes is the size of file contents processed so far, out of
+	// TotalBytes of all matched files.
+	ReadBytes, TotalBytes int64
+	// Elapsed is the time since the replay started.
+	Elapsed time.Duration
+	// ETA estimates the time remaining, from the rate of ReadBytes.
+	ETA time.Duration
+}
+
+// Replayer pushes previously dumped payloads again, e.g. when recovering from
+// an outage. A payload is a JSON object in the wire format, {"data": [...]}.
+// A file can hold a single payload, or many of them as NDJSON.
+type Replayer struct {
+	Client *Client
+	// Progress, if set, is called after every pushed payload.
+	Progress func(ReplayProgress)
+	// Checkpoint, if set, records every pushed payload, and an interrupted
+	// replay continues after the last recorded one. A payload pushed right
+	// before the interruption may be pushed again, so the payloads should
+	// set ensure_unique meta.
+	Checkpoint Checkpoint
+}
+
+// ReplayPosition identifies the last pushed payload of a replay.
+type ReplayPosition struct {
+	// File is the file of the last pushed payload.
+	File string `json:"file"`
+	// Payloads is the number of payloads of File pushed.
+	Payloads int `json:"payloads"`
+}
+
+// Checkpoint stores the position of a replay so it can be resumed after
+// interruption.
+type Checkpoint interface {
+	// Load returns the last saved position, or zero ReplayPosition if there
+	// is none.
+	Load() (ReplayPosition, error)
+	// Save stores the position.
+	Save(position ReplayPosition) error
+}
+
+// FileCheckpoint is a Checkpoint stored as JSON in a file at Path.
+type FileCheckpoint struct {
+	Path string
+}
+
+// Load implements Checkpoint. Missing file means there is no position.
+func (c FileCheckpoint) Load() (ReplayPosition, error) {
+	var position ReplayPosition
+	data, err := ioutil.ReadFile(c.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return position, nil
+	}
+	if err != nil {
+		return position, err
+	}
+	if err := json.Unmarshal(data, &position); err != nil {
+		return position, fmt.Errorf("can't unmarshal checkpoint[%s]: %w", string(data), err)
+	}
+	return position, nil
+}
+
+// Save implements Checkpoint. The file is replaced atomically.
+func (c FileCheckpoint) Save(position ReplayPosition) error {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.Path), filepath.Base(c.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// ReplayDir pushes payloads from files in fsys matching glob, in lexical
+// order of file names. See Replayer for the file format.
+func (c *Client) ReplayDir(ctx context.Context, fsys fs.FS, glob string) error {
+	return (&Replayer{Client: c}).ReplayDir(ctx, fsys, glob)
+}
+
+// ReplayDir pushes payloads from files in fsys matching glob, in lexical
+// order of file names. It stops at the first failed push.
+func (r *Replayer) ReplayDir(ctx context.Context, fsys fs.FS, glob string) error {
+	files, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return fmt.Errorf("matching files: %w", err)
+	}
+
+	var position ReplayPosition
+	if r.Checkpoint != nil {
+		if position, err = r.loadCheckpoint(); err != nil {
+			return fmt.Errorf("loading checkpoint: %w", err)
+		}
+	}
+
+	progress := ReplayProgress{Files: len(files)}
+	for _, file := range files {
+		if file < position.File {
+			continue
+		}
+		info, err := fs.Stat(fsys, file)
+		if err != nil {
+			return fmt.Errorf("replaying %s: %w", file, err)
+		}
+		progress.TotalBytes += info.Size()
+	}
+
+	start := time.Now()
+	for i, file := range files {
+		if file < position.File {
+			continue
+		}
+		skip := 0
+		if file == position.File {
+			skip = position.Payloads
+		}
+		progress.File, progress.FileIndex = file, i
+		if err := r.replayFile(ctx, fsys, start, skip, &progress); err != nil {
+			return fmt.Errorf("replaying %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) replayFile(ctx context.Context, fsys fs.FS, start time.Time, skip int, progress *ReplayProgress) error {
+	data, err := fs.ReadFile(fsys, progress.File)
+	if err != nil {
+		return err
+	}
+	fileStart := progress.ReadBytes
+	defer func() { progress.ReadBytes = fileStart + int64(len(data)) }()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for n := 0; ; n++ {
+		var payload json.RawMessage
+		if err := decoder.Decode(&payload); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("payload %d: %w", n, err)
+		}
+		var wrap KPIWrap
+		if err := json.Unmarshal(payload, &wrap); err != nil || len(wrap.Data) == 0 {
+			return fmt.Errorf("payload %d: not a push payload", n)
+		}
+		if n < skip {
+			continue
+		}
+
+		if _, err := r.Client.postRequest(ctx, "/", payload); err != nil {
+			return fmt.Errorf("payload %d: sending request: %w", n, err)
+		}
+		if r.Checkpoint != nil {
+			if err := r.saveCheckpoint(ReplayPosition{File: progress.File, Payloads: n + 1}); err != nil {
+				return fmt.Errorf("payload %d: saving checkpoint: %w", n, err)
+			}
+		}
+		progress.Payloads++
+		progress.KPIs += len(wrap.Data)
+		progress.Bytes += int64(len(payload))
+		progress.ReadBytes = fileStart + decoder.InputOffset()
+		progress.Elapsed = time.Since(start)
+		progress.ETA = 0
+		if progress.ReadBytes > 0 {
+			remaining := float64(progress.TotalBytes-progress.ReadBytes) / float64(progress.ReadBytes)
+			progress.ETA = time.Duration(float64(progress.Elapsed) * remaining)
+		}
+		if r.Progress != nil {
+			if err := r.reportProgress(*progress); err != nil {
+				return fmt.Errorf("payload %d: %w", n, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package databox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReplayDir(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	fsys := fstest.MapFS{
+		"dump/2.ndjson": {Data: []byte(`{"data":[{"$b":2}]}` + "\n" + `{"data":[{"$c":3}],"meta":{"ensure_unique":true}}` + "\n")},
+		"dump/1.json":   {Data: []byte(`{"data": [{"$a": 1}]}`)},
+		"dump/skip.txt": {Data: []byte(`garbage`)},
+	}
+
+	var progress []ReplayProgress
+	replayer := &Replayer{
+		Client:   client,
+		Progress: func(p ReplayProgress) { progress = append(progress, p) },
+	}
+	if err := replayer.ReplayDir(context.Background(), fsys, "dump/*json"); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+
+	wantBodies := []string{`{"data": [{"$a": 1}]}`, `{"data":[{"$b":2}]}`, `{"data":[{"$c":3}],"meta":{"ensure_unique":true}}`}
+	if !reflect.DeepEqual(bodies, wantBodies) {
+		t.Error("Payloads must be sent verbatim and in order", bodies)
+	}
+	if len(progress) != 3 || progress[2].File != "dump/2.ndjson" || progress[2].FileIndex != 1 ||
+		progress[2].Files != 2 || progress[2].Payloads != 3 || progress[2].Bytes != int64(len(wantBodies[0]+wantBodies[1]+wantBodies[2])) {
+		t.Error("Unexpected progress", progress)
+	}
+	last := progress[2]
+	if last.KPIs != 3 || last.TotalBytes != int64(len(fsys["dump/1.json"].Data)+len(fsys["dump/2.ndjson"].Data)) ||
+		last.ReadBytes != last.TotalBytes-1 {
+		t.Error("Unexpected progress", last)
+	}
+
+	if err := client.ReplayDir(context.Background(), fsys, "dump/*.txt"); err == nil {
+		t.Error("Invalid payload must fail")
+	}
+}
+
+func TestReplayDirCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	fail := true
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if fail && len(bodies) == 3 {
+			return jsonResponse(500, `{"type":"error","message":"fail"}`), nil
+		}
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	fsys := fstest.MapFS{
+		"1.json":   {Data: []byte(`{"data":[{"$a":1}]}`)},
+		"2.ndjson": {Data: []byte(`{"data":[{"$b":2}]}` + "\n" + `{"data":[{"$c":3}]}` + "\n" + `{"data":[{"$d":4}]}`)},
+		"3.json":   {Data: []byte(`{"data":[{"$e":5}]}`)},
+	}
+	checkpoint := FileCheckpoint{Path: filepath.Join(t.TempDir(), "checkpoint.json")}
+	replayer := &Replayer{Client: client, Checkpoint: checkpoint}
+
+	if err := replayer.ReplayDir(context.Background(), fsys, "*"); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	if position, err := checkpoint.Load(); err != nil || position != (ReplayPosition{File: "2.ndjson", Payloads: 1}) {
+		t.Fatal("Unexpected checkpoint", position, err)
+	}
+
+	bodies, fail = nil, false
+	if err := replayer.ReplayDir(context.Background(), fsys, "*"); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []string{`{"data":[{"$c":3}]}`, `{"data":[{"$d":4}]}`, `{"data":[{"$e":5}]}`}
+	if !reflect.DeepEqual(bodies, want) {
+		t.Error("Replay must resume after checkpoint", bodies)
+	}
+}
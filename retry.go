@@ -0,0 +1,235 @@
+package databox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backoff decides whether and when a failed request is retried. Requests are
+// retried on network errors, 429 Too Many Requests and 5xx responses.
+type Backoff interface {
+	// Next returns the delay before the attempt-th retry, counted from 1, and
+	// whether the request should be retried at all. The resp is nil when the
+	// request failed without a response.
+	Next(attempt int, resp *http.Response) (time.Duration, bool)
+}
+
+// ConstantBackoff waits the same Delay before each retry.
+type ConstantBackoff struct {
+	// Delay is the wait before each retry.
+	Delay time.Duration
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+}
+
+// Next implements Backoff.
+func (b ConstantBackoff) Next(attempt int, _ *http.Response) (time.Duration, bool) {
+	return b.Delay, attempt <= b.MaxRetries
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier after each retry.
+type ExponentialBackoff struct {
+	// Initial is the wait before the first retry.
+	Initial time.Duration
+	// Max caps the wait. Zero means no cap.
+	Max time.Duration
+	// Multiplier grows the delay. Values below 1 are treated as 2.
+	Multiplier float64
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+}
+
+// Next implements Backoff.
+func (b ExponentialBackoff) Next(attempt int, _ *http.Response) (time.Duration, bool) {
+	if attempt > b.MaxRetries {
+		return 0, false
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max, true
+	}
+	return time.Duration(delay), true
+}
+
+// RetryAfterBackoff honours the Retry-After header of the response. When the
+// header is missing, the decision is left to Fallback.
+type RetryAfterBackoff struct {
+	// Fallback is used when the response doesn't carry Retry-After. It also
+	// limits the number of retries.
+	Fallback Backoff
+	// MaxDelay caps the delay requested by the server. Zero means no cap.
+	MaxDelay time.Duration
+}
+
+// Next implements Backoff.
+func (b RetryAfterBackoff) Next(attempt int, resp *http.Response) (time.Duration, bool) {
+	delay, retry := b.Fallback.Next(attempt, resp)
+	if !retry {
+		return 0, false
+	}
+	if resp == nil {
+		return delay, true
+	}
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		delay = retryAfter
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+	return delay, true
+}
+
+// parseRetryAfter reads Retry-After header in both delay-seconds and HTTP-date
+// forms.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// RetryBudget limits the number of retries per minute. A single budget is
+// meant to be shared by all goroutines and clients pushing to Databox, so an
+// outage doesn't multiply the request load by the number of retries.
+type RetryBudget struct {
+	mu        sync.Mutex
+	perMinute float64
+	tokens    float64
+	last      time.Time
+	now       func() time.Time
+}
+
+// NewRetryBudget returns a budget allowing maxPerMinute retries per minute.
+// The budget is replenished continuously.
+func NewRetryBudget(maxPerMinute int) *RetryBudget {
+	return &RetryBudget{
+		perMinute: float64(maxPerMinute),
+		tokens:    float64(maxPerMinute),
+		now:       time.Now,
+	}
+}
+
+// SetLimit changes the budget to maxPerMinute retries per minute. The
+// retries already taken are kept.
+func (b *RetryBudget) SetLimit(maxPerMinute int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.perMinute = float64(maxPerMinute)
+	if b.tokens > b.perMinute {
+		b.tokens = b.perMinute
+	}
+}
+
+// allow takes one retry from the budget. It returns false when the budget is
+// exhausted.
+func (b *RetryBudget) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Minutes() * b.perMinute
+		if b.tokens > b.perMinute {
+			b.tokens = b.perMinute
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// isRetryable reports whether the outcome of request is worth retrying.
+func isRetryable(request *http.Request, response *http.Response, err error) bool {
+	if request.Context().Err() != nil {
+		return false
+	}
+	var redirectErr *RedirectError
+	if errors.As(err, &redirectErr) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+}
+
+// do executes the request and retries it according to c.Backoff. The request
+// is signed before every attempt. Bodies without GetBody are buffered, so
+// they can be sent again.
+func (c *Client) do(request *http.Request) (*http.Response, error) {
+	if c.Backoff != nil && request.GetBody == nil && request.Body != nil && request.Body != http.NoBody {
+		data, err := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		request.ContentLength = int64(len(data))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		request.Body, _ = request.GetBody()
+	}
+	for attempt := 1; ; attempt++ {
+		if c.SignRequest != nil {
+			if err := c.signRequest(request); err != nil {
+				return nil, fmt.Errorf("signing request: %w", err)
+			}
+		}
+		start := time.Now()
+		response, err := c.HTTPClient.Do(request)
+		c.Stats.recordLatency(time.Since(start))
+		if c.Backoff == nil || !isRetryable(request, response, err) {
+			return response, err
+		}
+		delay, retry := c.Backoff.Next(attempt, response)
+		if budget := c.config().RetryBudget; !retry || (budget != nil && !budget.allow()) {
+			return response, err
+		}
+		c.Stats.recordRetry()
+		if response != nil {
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
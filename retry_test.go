@@ -0,0 +1,191 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	b := ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second, MaxRetries: 4}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if delay, retry := b.Next(attempt+1, nil); !retry || delay != want {
+			t.Errorf("attempt %d: got %v %v, want %v", attempt+1, delay, retry, want)
+		}
+	}
+	if _, retry := b.Next(5, nil); retry {
+		t.Error("Must give up after MaxRetries")
+	}
+}
+
+func TestRetryAfterBackoff(t *testing.T) {
+	t.Parallel()
+
+	b := RetryAfterBackoff{
+		Fallback: ConstantBackoff{Delay: time.Second, MaxRetries: 1},
+		MaxDelay: time.Minute,
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": {"30"}}}
+	if delay, retry := b.Next(1, resp); !retry || delay != 30*time.Second {
+		t.Error("Retry-After must be honoured", delay, retry)
+	}
+	resp.Header.Set("Retry-After", "3600")
+	if delay, _ := b.Next(1, resp); delay != time.Minute {
+		t.Error("Delay must be capped", delay)
+	}
+	if delay, _ := b.Next(1, &http.Response{}); delay != time.Second {
+		t.Error("Fallback must be used without Retry-After", delay)
+	}
+	if _, retry := b.Next(2, resp); retry {
+		t.Error("Fallback must limit retries")
+	}
+}
+
+func TestPushRetries(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.Backoff = ConstantBackoff{Delay: time.Millisecond, MaxRetries: 3}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, errors.New("connection reset")
+		case 2:
+			return jsonResponse(503, `{"type":"unavailable","message":"try later"}`), nil
+		}
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	if _, err := client.Push(&KPI{Key: "temp.ny", Value: 52}); err != nil {
+		t.Error("Must be nil", err)
+	}
+	if calls != 3 {
+		t.Error("Expected 3 attempts, got", calls)
+	}
+
+	calls = 0
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(400, `{"type":"invalid_json","message":"some error message"}`), nil
+	})
+	if _, err := client.Push(&KPI{Key: "temp.ny", Value: 52}); err == nil {
+		t.Error("This should not be \"ok\"")
+	}
+	if calls != 1 {
+		t.Error("Client errors must not be retried, got attempts", calls)
+	}
+}
+
+func TestDoRetriesBody(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	client := NewClient(getToken())
+	client.Backoff = ConstantBackoff{MaxRetries: 1}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			return jsonResponse(503, `{}`), nil
+		}
+		return jsonResponse(200, `{}`), nil
+	})
+
+	// io.MultiReader isn't rewound by http.NewRequest.
+	response, err := client.Do(context.Background(), "POST", "/", io.MultiReader(strings.NewReader("payload")))
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	response.Body.Close()
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Error("Body must be sent again", bodies)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	budget := NewRetryBudget(2)
+	budget.now = func() time.Time { return now }
+
+	if !budget.allow() || !budget.allow() {
+		t.Error("Budget must allow 2 retries")
+	}
+	if budget.allow() {
+		t.Error("Budget must be exhausted")
+	}
+	now = now.Add(30 * time.Second)
+	if !budget.allow() {
+		t.Error("Budget must be replenished")
+	}
+	if budget.allow() {
+		t.Error("Budget must be exhausted again")
+	}
+}
+
+func TestRetryBudgetSharedByClients(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	budget := NewRetryBudget(1)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(503, `{"type":"unavailable","message":"try later"}`), nil
+	})
+	for i := 0; i < 2; i++ {
+		client := NewClient(getToken())
+		client.Backoff = ConstantBackoff{MaxRetries: 5}
+		client.RetryBudget = budget
+		client.HTTPClient.Transport = transport
+		if _, err := client.Push(&KPI{Key: "temp.ny", Value: 52}); err == nil {
+			t.Error("This should not be \"ok\"")
+		}
+	}
+	if calls != 3 {
+		t.Error("Expected 2 attempts and 1 retry, got", calls)
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	t.Parallel()
+
+	var signatures []string
+	client := NewClient(getToken())
+	client.Backoff = ConstantBackoff{MaxRetries: 1}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+		return jsonResponse(503, `{"type":"unavailable","message":"try later"}`), nil
+	})
+
+	attempt := 0
+	client.SignRequest = func(r *http.Request) error {
+		attempt++
+		body, err := r.GetBody()
+		if err != nil {
+			return err
+		}
+		data, _ := io.ReadAll(body)
+		r.Header.Set("X-Signature", fmt.Sprintf("%d:%d", attempt, len(data)))
+		return nil
+	}
+	_, _ = client.Push(&KPI{Key: "a", Value: 1})
+	if len(signatures) != 2 || signatures[0] != "1:19" || signatures[1] != "2:19" {
+		t.Error("Every attempt must be signed", signatures)
+	}
+
+	signErr := errors.New("no credentials")
+	client.SignRequest = func(r *http.Request) error { return signErr }
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); !errors.Is(err, signErr) {
+		t.Error("Signing error must be returned", err)
+	}
+}
@@ -0,0 +1,144 @@
+package databox
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Aggregation combines values of a rollup bucket.
+type Aggregation int
+
+const (
+	// RollupSum adds the values up.
+	RollupSum Aggregation = iota
+	// RollupAverage is the mean of the values.
+	RollupAverage
+	// RollupMin is the smallest value.
+	RollupMin
+	// RollupMax is the largest value.
+	RollupMax
+)
+
+// Rollup configures a KPI aggregating values of a metric over calendar
+// buckets, e.g. "sales_weekly" from daily "sales".
+type Rollup struct {
+	// Key is the metric key of the aggregated values.
+	Key string
+	// Target is the metric key of the rollup KPI.
+	Target string
+	// Bucket is the period of the rollup.
+	Bucket Bucket
+	// Aggregation combines the values of the bucket.
+	Aggregation Aggregation
+	// Location is the time zone of the buckets. Nil means UTC.
+	Location *time.Location
+}
+
+// RollupAggregator emits rollup KPIs along the pushed KPIs, so boards can mix
+// granularities without a separate ETL job. It remembers the values of the
+// buckets across pushes and pushes the updated aggregate of the bucket
+// whenever one of its values is pushed. A value pushed again for the same
+// date replaces the previous one. Dates without a zone are in the Location
+// of the rollup, values without a date are dated now. The memory grows with
+// the number of buckets. RollupAggregator is safe for concurrent use.
+type RollupAggregator struct {
+	Rollups []Rollup
+
+	mu sync.Mutex
+	// buckets holds values by rollup index, bucket series and data point.
+	buckets map[int]map[string]map[string]float64
+}
+
+// Transform returns the Transform adding the rollup KPIs.
+func (a *RollupAggregator) Transform() Transform {
+	return func(ctx context.Context, kpis []KPI) ([]KPI, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.buckets == nil {
+			a.buckets = make(map[int]map[string]map[string]float64)
+		}
+		buckets, dryRun := a.buckets, isDryRun(ctx)
+		if dryRun {
+			// Dry runs aggregate copies of the buckets.
+			buckets = make(map[int]map[string]map[string]float64)
+		}
+
+		result := append([]KPI(nil), kpis...)
+		// emitted holds indexes of the rollup KPIs in result by bucket, so
+		// each bucket is pushed once with its final aggregate.
+		emitted := make(map[string]int)
+		for _, kpi := range kpis {
+			now := time.Now()
+			for key, value := range kpiValues(kpi) {
+				for i, rollup := range a.Rollups {
+					if rollup.Key != key {
+						continue
+					}
+					date := now
+					if kpi.Date != "" {
+						var err error
+						if date, err = parseDateIn(kpi.Date, rollup.Location); err != nil {
+							return nil, err
+						}
+					}
+					rollupKPI := KPI{
+						Key:        rollup.Target,
+						Date:       rollup.Bucket.Date(date, rollup.Location),
+						Unit:       kpi.Unit,
+						Attributes: kpi.Attributes,
+					}
+					if buckets[i] == nil {
+						buckets[i] = make(map[string]map[string]float64)
+					}
+					bucketID := pointID(rollupKPI.Key, rollupKPI.Date, rollupKPI.Attributes)
+					values := buckets[i][bucketID]
+					if values == nil {
+						values = make(map[string]float64)
+						if dryRun {
+							for id, value := range a.buckets[i][bucketID] {
+								values[id] = value
+							}
+						}
+						buckets[i][bucketID] = values
+					}
+					values[pointID(key, date.Format(DateTimeTZFormat), kpi.Attributes)] = exactFloat64(value)
+					rollupKPI.Value = float32(aggregate(values, rollup.Aggregation))
+					if index, ok := emitted[bucketID]; ok {
+						result[index] = rollupKPI
+						continue
+					}
+					emitted[bucketID] = len(result)
+					result = append(result, rollupKPI)
+				}
+			}
+		}
+		return result, nil
+	}
+}
+
+// aggregate combines the values.
+func aggregate(values map[string]float64, aggregation Aggregation) float64 {
+	var result float64
+	switch aggregation {
+	case RollupMin:
+		result = math.Inf(1)
+	case RollupMax:
+		result = math.Inf(-1)
+	}
+	for _, value := range values {
+		switch aggregation {
+		case RollupMin:
+			result = math.Min(result, value)
+		case RollupMax:
+			result = math.Max(result, value)
+		default:
+			result += value
+		}
+	}
+	if aggregation == RollupAverage && len(values) > 0 {
+		result /= float64(len(values))
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package databox
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRollupAggregator(t *testing.T) {
+	t.Parallel()
+
+	aggregator := &RollupAggregator{Rollups: []Rollup{
+		{Key: "sales", Target: "sales_weekly", Bucket: BucketWeek},
+		{Key: "sales", Target: "sales_monthly_max", Bucket: BucketMonth, Aggregation: RollupMax},
+	}}
+	transform := aggregator.Transform()
+	rollups := func(kpis ...KPI) map[string]KPI {
+		result, err := transform(context.Background(), kpis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) < len(kpis) {
+			t.Fatal("Original KPIs must be kept", result)
+		}
+		byKey := make(map[string]KPI)
+		for _, kpi := range result[len(kpis):] {
+			if _, ok := byKey[kpi.Key+" "+kpi.Date]; ok {
+				t.Error("Bucket must be pushed once", kpi)
+			}
+			byKey[kpi.Key+" "+kpi.Date] = kpi
+		}
+		return byKey
+	}
+
+	// Monday and Tuesday of one week, Monday of the next one.
+	got := rollups(KPI{Key: "sales", Value: 10, Date: "2020-05-11"}, KPI{Key: "sales", Value: 5, Date: "2020-05-12"})
+	if got["sales_weekly 2020-05-11 00:00:00+00:00"].Value != 15 || got["sales_monthly_max 2020-05-01 00:00:00+00:00"].Value != 10 {
+		t.Error("Unexpected rollups", got)
+	}
+	got = rollups(KPI{Key: "sales", Value: 7, Date: "2020-05-12"}, KPI{Key: "sales", Value: 20, Date: "2020-05-18"}, KPI{Key: "orders", Value: 1})
+	if got["sales_weekly 2020-05-11 00:00:00+00:00"].Value != 17 {
+		t.Error("Value pushed again must replace the previous one", got)
+	}
+	if got["sales_weekly 2020-05-18 00:00:00+00:00"].Value != 20 || got["sales_monthly_max 2020-05-01 00:00:00+00:00"].Value != 20 {
+		t.Error("Unexpected rollups", got)
+	}
+	if len(got) != 3 {
+		t.Error("Only configured keys must be rolled up", got)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregator = &RollupAggregator{Rollups: []Rollup{{Key: "sales", Target: "sales_monthly", Bucket: BucketMonth, Location: newYork}}}
+	transform = aggregator.Transform()
+	got = rollups(KPI{Key: "sales", Value: 3, Date: "2020-06-01"})
+	if got["sales_monthly 2020-06-01 00:00:00-04:00"].Value != 3 {
+		t.Error("Dates without a zone must be in the rollup's location", got)
+	}
+
+	if v := aggregate(map[string]float64{"a": 1, "b": 4}, RollupAverage); v != 2.5 {
+		t.Error("Unexpected average", v)
+	}
+	if v := aggregate(map[string]float64{"a": 1, "b": 4}, RollupMin); v != 1 {
+		t.Error("Unexpected min", v)
+	}
+}
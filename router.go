@@ -0,0 +1,105 @@
+package databox
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Route sends KPIs accepted by Match to Target, e.g. a Client with the
+// token of another data source.
+type Route struct {
+	Match  func(kpi KPI) bool
+	Target Pusher
+}
+
+// MatchKey returns a Route matcher accepting KPIs whose Key matches re.
+func MatchKey(re *regexp.Regexp) func(kpi KPI) bool {
+	return func(kpi KPI) bool {
+		return re.MatchString(kpi.Key)
+	}
+}
+
+// Router is a Pusher distributing KPIs among several targets, so a single
+// pipeline can feed several data sources. Every KPI goes to the target of the
+// first matching route, or to Default if no route matches. KPIs matching no
+// route are dropped when Default is nil.
+type Router struct {
+	// Transforms are applied before routing.
+	Transforms []Transform
+	Routes     []Route
+	Default    Pusher
+}
+
+var _ Pusher = (*Router)(nil)
+
+// PushCtx implements Pusher.
+func (r *Router) PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error) {
+	return r.InsertAll(ctx, []KPI{*kpi}, false)
+}
+
+// InsertAll implements Pusher. Every route gets a single request with its
+// KPIs. A failed target doesn't stop pushing to the others; the first error
+// is returned. The ResponseStatus is the one of the last successful target.
+func (r *Router) InsertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
+	ctx = startTrace(ctx)
+	trace(ctx, "router input", kpis, "", nil)
+	for i := range r.Transforms {
+		var err error
+		kpis, err = runTransforms(ctx, kpis, r.Transforms[i:i+1])
+		trace(ctx, fmt.Sprintf("router transform %d", i), kpis, "", err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Batches by route index, the last one is for Default.
+	batches := make([][]KPI, len(r.Routes)+1)
+	for _, kpi := range kpis {
+		i, err := r.route(kpi)
+		if err != nil {
+			return nil, err
+		}
+		batches[i] = append(batches[i], kpi)
+	}
+
+	var firstErr error
+	responseStatus := &ResponseStatus{}
+	for i, batch := range batches {
+		target, stage := r.Default, "default route"
+		if i < len(r.Routes) {
+			target, stage = r.Routes[i].Target, fmt.Sprintf("route %d", i)
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		if target == nil {
+			trace(ctx, "dropped", batch, "", nil)
+			continue
+		}
+		trace(ctx, stage, batch, "", nil)
+		status, err := target.InsertAll(ctx, batch, forcePush)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("pushing to route %d: %w", i, err)
+			}
+			continue
+		}
+		responseStatus = status
+	}
+	return responseStatus, firstErr
+}
+
+// route returns index of the first route matching kpi, or len(r.Routes).
+func (r *Router) route(kpi KPI) (int, error) {
+	for i, route := range r.Routes {
+		matched, err := matchRoute(route, kpi)
+		if err != nil {
+			return 0, fmt.Errorf("route %d: %w", i, err)
+		}
+		if matched {
+			return i, nil
+		}
+	}
+	return len(r.Routes), nil
+}
@@ -0,0 +1,60 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+type pusherMock struct {
+	kpis [][]KPI
+	err  error
+}
+
+func (p *pusherMock) PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error) {
+	return p.InsertAll(ctx, []KPI{*kpi}, false)
+}
+
+func (p *pusherMock) InsertAll(_ context.Context, kpis []KPI, _ bool) (*ResponseStatus, error) {
+	p.kpis = append(p.kpis, kpis)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &ResponseStatus{ID: "someRandomId"}, nil
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	ops, business, other := &pusherMock{}, &pusherMock{}, &pusherMock{}
+	router := &Router{
+		Routes: []Route{
+			{Match: MatchKey(regexp.MustCompile(`^(cpu|mem)\.`)), Target: ops},
+			{Match: func(kpi KPI) bool { return kpi.Unit == "USD" }, Target: business},
+		},
+		Default: other,
+	}
+
+	kpis := []KPI{
+		{Key: "cpu.load", Value: 1},
+		{Key: "revenue", Value: 2, Unit: "USD"},
+		{Key: "mem.used", Value: 3},
+		{Key: "signups", Value: 4},
+	}
+	if _, err := router.InsertAll(context.Background(), kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(ops.kpis) != 1 || len(ops.kpis[0]) != 2 || len(business.kpis[0]) != 1 || other.kpis[0][0].Key != "signups" {
+		t.Error("Unexpected routing", ops.kpis, business.kpis, other.kpis)
+	}
+
+	router.Default = nil
+	ops.err = errors.New("ops down")
+	if _, err := router.InsertAll(context.Background(), kpis, false); !errors.Is(err, ops.err) {
+		t.Error("Expected target error", err)
+	}
+	if len(business.kpis) != 2 || len(other.kpis) != 1 {
+		t.Error("Other targets must be pushed, unmatched KPIs dropped", business.kpis, other.kpis)
+	}
+}
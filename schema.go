@@ -0,0 +1,109 @@
+package databox
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSchemaViolation is returned when a KPI doesn't conform to Client.Schema.
+var ErrSchemaViolation = errors.New("schema violation")
+
+// ValueRange is an inclusive range of allowed values.
+type ValueRange struct {
+	Min, Max float64
+}
+
+// MetricSchema describes an allowed metric.
+type MetricSchema struct {
+	// Key is the metric key.
+	Key string
+	// Unit is the expected unit. Empty means any unit.
+	Unit string
+	// Range limits the values, if set.
+	Range *ValueRange
+}
+
+// Schema is a registry of allowed metrics. Pushing a metric that isn't
+// registered, or that has unexpected unit or value, is a violation. This
+// catches typos in keys, which would otherwise silently create new metrics.
+// The zero value allows no metrics. Schema is safe for concurrent use.
+type Schema struct {
+	// Warn makes violations logged through Client.Logger instead of rejecting
+	// the push.
+	Warn bool
+
+	mu      sync.RWMutex
+	metrics map[string]MetricSchema
+}
+
+// NewSchema returns a schema allowing given metrics.
+func NewSchema(metrics ...MetricSchema) *Schema {
+	s := &Schema{}
+	s.Register(metrics...)
+	return s
+}
+
+// Register adds metrics to the schema, replacing metrics with the same key.
+func (s *Schema) Register(metrics ...MetricSchema) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics == nil {
+		s.metrics = make(map[string]MetricSchema, len(metrics))
+	}
+	for _, metric := range metrics {
+		s.metrics[metric.Key] = metric
+	}
+}
+
+// Validate checks the KPI against the schema.
+func (s *Schema) Validate(kpi KPI) error {
+	if kpi.Key != "" {
+		if err := s.validateMetric(kpi.Key, kpi.Value, kpi.Unit); err != nil {
+			return err
+		}
+	}
+	for key, value := range kpi.Metrics {
+		if err := s.validateMetric(key, value, kpi.Unit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateMetric(key string, value float32, unit string) error {
+	s.mu.RLock()
+	metric, ok := s.metrics[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: metric %q isn't registered", ErrSchemaViolation, key)
+	}
+	if metric.Unit != "" && unit != metric.Unit {
+		return fmt.Errorf("%w: metric %q has unit %q, expected %q", ErrSchemaViolation, key, unit, metric.Unit)
+	}
+	if r := metric.Range; r != nil && (float64(value) < r.Min || float64(value) > r.Max) {
+		return fmt.Errorf("%w: metric %q value %v is out of range [%v, %v]", ErrSchemaViolation, key, value, r.Min, r.Max)
+	}
+	return nil
+}
+
+// validateSchema checks kpis against c.Schema.
+func (c *Client) validateSchema(kpis []KPI) error {
+	if c.Schema == nil {
+		return nil
+	}
+	for _, kpi := range kpis {
+		err := c.Schema.Validate(kpi)
+		if err == nil {
+			continue
+		}
+		if !c.Schema.Warn {
+			return err
+		}
+		if c.Logger != nil {
+			c.Logger.Printf("databox: %v", err)
+		}
+	}
+	return nil
+}
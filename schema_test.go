@@ -0,0 +1,74 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	schema := NewSchema(
+		MetricSchema{Key: "revenue", Unit: "USD", Range: &ValueRange{Min: 0, Max: 1e6}},
+		MetricSchema{Key: "signups"},
+	)
+	for _, kpi := range []KPI{
+		{Key: "revenue", Value: 100, Unit: "USD"},
+		{Key: "signups", Value: -1, Unit: "users"},
+		{Metrics: map[string]float32{"signups": 3}},
+	} {
+		if err := schema.Validate(kpi); err != nil {
+			t.Errorf("%+v: must be nil, got %v", kpi, err)
+		}
+	}
+	for _, kpi := range []KPI{
+		{Key: "revenu", Value: 100, Unit: "USD"},
+		{Key: "revenue", Value: 100, Unit: "EUR"},
+		{Key: "revenue", Value: -5, Unit: "USD"},
+		{Key: "signups", Metrics: map[string]float32{"signup": 3}},
+	} {
+		if err := schema.Validate(kpi); !errors.Is(err, ErrSchemaViolation) {
+			t.Errorf("%+v: expected ErrSchemaViolation, got %v", kpi, err)
+		}
+	}
+
+	schema = &Schema{Warn: true}
+	if err := schema.Validate(KPI{Key: "revenue"}); !errors.Is(err, ErrSchemaViolation) {
+		t.Error("Zero schema must allow no metrics", err)
+	}
+	schema.Register(MetricSchema{Key: "revenue"})
+	if err := schema.Validate(KPI{Key: "revenue"}); err != nil {
+		t.Error("Must be nil", err)
+	}
+}
+
+func TestClientSchema(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	client.Schema = NewSchema(MetricSchema{Key: "signups"})
+
+	if _, err := client.Push(&KPI{Key: "signup", Value: 1}); !errors.Is(err, ErrSchemaViolation) {
+		t.Error("Expected ErrSchemaViolation", err)
+	}
+	if calls != 0 {
+		t.Error("Violating push must not be sent")
+	}
+
+	var logger loggerMock
+	client.Logger = &logger
+	client.Schema.Warn = true
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "signup", Value: 1}}, false); err != nil {
+		t.Error("Must be nil", err)
+	}
+	if calls != 1 || len(logger) != 1 {
+		t.Error("Violation must be logged and KPI pushed", calls, logger)
+	}
+}
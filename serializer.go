@@ -0,0 +1,35 @@
+package databox
+
+import "encoding/json"
+
+// Serializer encodes KPIs into the body of a push request. It allows
+// swapping the JSON encoder, or the payload shape.
+type Serializer interface {
+	// Serialize encodes kpis and the request meta, which is nil if there
+	// is none.
+	Serialize(kpis []KPI, meta map[string]interface{}) ([]byte, error)
+}
+
+// JSONSerializer encodes KPIs as KPIWrap, {"data": [...], "meta": {...}}.
+type JSONSerializer struct {
+	// Marshal encodes the KPIWrap. Nil means json.Marshal. Compatible
+	// encoders can be plugged in, e.g. jsoniter.ConfigFastest.Marshal.
+	Marshal func(v interface{}) ([]byte, error)
+}
+
+// Serialize implements Serializer.
+func (s JSONSerializer) Serialize(kpis []KPI, meta map[string]interface{}) ([]byte, error) {
+	wrap := KPIWrap{
+		Data: make([]map[string]interface{}, 0, len(kpis)),
+		Meta: meta,
+	}
+	for _, kpi := range kpis {
+		wrap.Data = append(wrap.Data, kpi.ToJSONData())
+	}
+
+	marshal := s.Marshal
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+	return marshal(wrap)
+}
@@ -0,0 +1,51 @@
+package databox
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestJSONSerializer(t *testing.T) {
+	t.Parallel()
+
+	payload, err := JSONSerializer{}.Serialize([]KPI{{Key: "a", Value: 1}}, map[string]interface{}{"ensure_unique": true})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if string(payload) != `{"data":[{"$a":1}],"meta":{"ensure_unique":true}}` {
+		t.Error("Unexpected payload", string(payload))
+	}
+
+	payload, err = JSONSerializer{}.Serialize(nil, nil)
+	if err != nil || string(payload) != `{"data":[]}` {
+		t.Error("Unexpected payload", string(payload), err)
+	}
+}
+
+type serializerMock struct{}
+
+func (serializerMock) Serialize(kpis []KPI, _ map[string]interface{}) ([]byte, error) {
+	return json.Marshal(map[string]int{"count": len(kpis)})
+}
+
+func TestClientSerializer(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	client := NewClient(getToken())
+	client.Serializer = serializerMock{}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	if _, err := client.Push(&KPI{Key: "a", Value: 1}); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if body != `{"count":1}` {
+		t.Error("Custom serializer must be used", body)
+	}
+}
@@ -0,0 +1,29 @@
+package databox
+
+import (
+	"context"
+	"time"
+)
+
+// Point is a value of a metric at a time.
+type Point struct {
+	Time  time.Time
+	Value float32
+	// Attributes are optional attributes of the value.
+	Attributes map[string]interface{}
+}
+
+// PushSeries pushes the points of the metric key in one request, e.g. a
+// time series being backfilled. The times are sent in DateTimeTZFormat.
+func (c *Client) PushSeries(ctx context.Context, key string, points []Point) (*ResponseStatus, error) {
+	kpis := make([]KPI, len(points))
+	for i, point := range points {
+		kpis[i] = KPI{
+			Key:        key,
+			Value:      point.Value,
+			Date:       point.Time.Format(DateTimeTZFormat),
+			Attributes: point.Attributes,
+		}
+	}
+	return c.InsertAll(ctx, kpis, false)
+}
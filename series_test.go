@@ -0,0 +1,40 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPushSeries(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := client.PushSeries(context.Background(), "revenue", []Point{
+		{Time: day, Value: 1},
+		{Time: day.AddDate(0, 0, 1), Value: 2, Attributes: map[string]interface{}{"region": "eu"}},
+	})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(requests) != 1 || len(requests[0].Data) != 2 {
+		t.Fatal("Series must be pushed in one request", requests)
+	}
+	second := requests[0].Data[1]
+	if second["$revenue"] != 2.0 || second["date"] != "2020-01-02 00:00:00+00:00" || second["region"] != "eu" {
+		t.Error("Unexpected data", second)
+	}
+}
@@ -0,0 +1,113 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Source is a feed of KPIs, e.g. a message queue subscription. Every batch
+// returned by Next has to be acknowledged by calling ack with the result of
+// its push, so the source can commit or redeliver the underlying messages.
+type Source interface {
+	// Next blocks until a batch of KPIs is available. It returns io.EOF when
+	// the source is exhausted.
+	Next(ctx context.Context) (kpis []KPI, ack func(error), err error)
+}
+
+// PushFrom pushes batches from source until the source is exhausted or ctx is
+// cancelled. Each batch is acknowledged with the push error, nil on success.
+// Failed pushes don't stop the loop, redelivery is up to the source.
+func (c *Client) PushFrom(ctx context.Context, source Source) error {
+	for {
+		kpis, ack, err := source.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading source: %w", err)
+		}
+		if len(kpis) == 0 {
+			ack(nil)
+			continue
+		}
+		_, err = c.InsertAll(ctx, kpis, false)
+		ack(err)
+	}
+}
+
+// SourceMessage is a single message delivered through ChannelSource. Adapters
+// for message queues convert the received message to KPIs and set Ack to the
+// broker's acknowledgement, e.g. ack on nil error and nack otherwise.
+type SourceMessage struct {
+	KPIs []KPI
+	Ack  func(error)
+}
+
+// ChannelSource is a Source reading messages from a channel. Messages already
+// waiting in the channel are combined into one batch of at most MaxBatch
+// KPIs. The source is exhausted when the channel is closed. ChannelSource
+// must not be used by multiple goroutines at once.
+type ChannelSource struct {
+	C <-chan SourceMessage
+	// MaxBatch limits the number of KPIs in a batch. Zero means that every
+	// message is a batch on its own. A single message is never split.
+	MaxBatch int
+
+	pending *SourceMessage
+}
+
+// Next implements Source.
+func (s *ChannelSource) Next(ctx context.Context) ([]KPI, func(error), error) {
+	message, err := s.receive(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kpis := append([]KPI(nil), message.KPIs...)
+	acks := []func(error){message.Ack}
+	for len(kpis) < s.MaxBatch {
+		var ok bool
+		select {
+		case message, ok = <-s.C:
+		default:
+		}
+		if !ok {
+			break
+		}
+		if len(kpis)+len(message.KPIs) > s.MaxBatch {
+			// Doesn't fit, the message starts the next batch.
+			s.pending = &message
+			break
+		}
+		kpis = append(kpis, message.KPIs...)
+		acks = append(acks, message.Ack)
+	}
+
+	return kpis, func(err error) {
+		for _, ack := range acks {
+			if ack != nil {
+				ack(err)
+			}
+		}
+	}, nil
+}
+
+// receive returns the pending message, or waits for the next one.
+func (s *ChannelSource) receive(ctx context.Context) (SourceMessage, error) {
+	if s.pending != nil {
+		message := *s.pending
+		s.pending = nil
+		return message, nil
+	}
+	select {
+	case <-ctx.Done():
+		return SourceMessage{}, ctx.Err()
+	case message, ok := <-s.C:
+		if !ok {
+			return SourceMessage{}, io.EOF
+		}
+		return message, nil
+	}
+}
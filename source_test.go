@@ -0,0 +1,61 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPushFromChannelSource(t *testing.T) {
+	t.Parallel()
+
+	var batches []int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		batches = append(batches, len(wrap.Data))
+		if len(batches) == 2 {
+			return jsonResponse(500, `{"type":"error","message":"fail"}`), nil
+		}
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	messages := make(chan SourceMessage, 4)
+	acks := make([]error, 4)
+	for i, n := range []int{1, 2, 2, 1} {
+		i := i
+		messages <- SourceMessage{
+			KPIs: make([]KPI, n),
+			Ack:  func(err error) { acks[i] = err },
+		}
+	}
+	close(messages)
+
+	err := client.PushFrom(context.Background(), &ChannelSource{C: messages, MaxBatch: 3})
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if !reflect.DeepEqual(batches, []int{3, 3}) {
+		t.Error("Unexpected batches", batches)
+	}
+	if acks[0] != nil || acks[1] != nil || acks[2] == nil || acks[3] == nil {
+		t.Error("Messages must be acknowledged with push result", acks)
+	}
+}
+
+func TestChannelSourceCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	source := &ChannelSource{C: make(chan SourceMessage)}
+	if _, _, err := source.Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled", err)
+	}
+}
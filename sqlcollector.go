@@ -0,0 +1,146 @@
+package databox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLQuery describes a query whose rows are pushed as KPIs. Each row results
+// in one KPI.
+type SQLQuery struct {
+	// Query is the SQL query to run.
+	Query string
+	// Args are the query arguments.
+	Args []interface{}
+	// Key is a fixed metric key for all rows. If it's empty, the first
+	// column of each row is used as the key.
+	Key string
+	// ValueColumn is the name of the numeric column holding the value. If
+	// it's empty, the column following the key column is used, or the first
+	// column when Key is set.
+	ValueColumn string
+	// DateColumn is the name of an optional column holding the date of the
+	// metric.
+	DateColumn string
+	// Unit is set to all KPIs of the query.
+	Unit string
+}
+
+// SQLCollector runs configured queries against DB and converts the resulting
+// rows to KPIs. Use Client.CollectEvery to push them on a schedule.
+type SQLCollector struct {
+	DB      *sql.DB
+	Queries []SQLQuery
+	// NumberFormat is used to parse values of text columns.
+	NumberFormat NumberFormat
+}
+
+// Collect implements Collector.
+func (c *SQLCollector) Collect(ctx context.Context) ([]KPI, error) {
+	var kpis []KPI
+	for _, query := range c.Queries {
+		queryKPIs, err := c.collectQuery(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", query.Query, err)
+		}
+		kpis = append(kpis, queryKPIs...)
+	}
+	return kpis, nil
+}
+
+func (c *SQLCollector) collectQuery(ctx context.Context, query SQLQuery) ([]KPI, error) {
+	rows, err := c.DB.QueryContext(ctx, query.Query, query.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	valueIndex, dateIndex := -1, -1
+	for i, column := range columns {
+		if query.ValueColumn != "" && column == query.ValueColumn {
+			valueIndex = i
+		}
+		if query.DateColumn != "" && column == query.DateColumn {
+			dateIndex = i
+		}
+	}
+	if query.ValueColumn != "" && valueIndex < 0 {
+		return nil, fmt.Errorf("no value column %q in %v", query.ValueColumn, columns)
+	}
+	if valueIndex < 0 {
+		valueIndex = 0
+		if query.Key == "" {
+			valueIndex = 1
+		}
+	}
+	if valueIndex >= len(columns) {
+		return nil, fmt.Errorf("no value column in %v", columns)
+	}
+	if query.DateColumn != "" && dateIndex < 0 {
+		return nil, fmt.Errorf("no date column %q in %v", query.DateColumn, columns)
+	}
+
+	var kpis []KPI
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		kpi := KPI{Key: query.Key, Unit: query.Unit}
+		if kpi.Key == "" {
+			kpi.Key = sqlString(values[0])
+		}
+		value, err := sqlFloat(values[valueIndex], c.NumberFormat)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", columns[valueIndex], err)
+		}
+		kpi.Value = value
+		if dateIndex >= 0 {
+			kpi.Date = sqlDate(values[dateIndex])
+		}
+		kpis = append(kpis, kpi)
+	}
+	return kpis, rows.Err()
+}
+
+func sqlString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+func sqlFloat(value interface{}, format NumberFormat) (float32, error) {
+	switch v := value.(type) {
+	case int64:
+		return float32(v), nil
+	case float64:
+		return float32(v), nil
+	case []byte, string:
+		return format.ParseFloat(sqlString(v))
+	}
+	return 0, fmt.Errorf("value %v of type %T is not numeric", value, value)
+}
+
+func sqlDate(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(DateTimeTZFormat)
+	}
+	return sqlString(value)
+}
@@ -0,0 +1,193 @@
+package databox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSQLCollector(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := openFakeDB(t, map[string]fakeRows{
+		"SELECT COUNT(*) FROM users": {
+			columns: []string{"count"},
+			values:  [][]driver.Value{{int64(42)}},
+		},
+		"SELECT country, SUM(amount), day FROM sales GROUP BY country, day": {
+			columns: []string{"country", "amount", "day"},
+			values: [][]driver.Value{
+				{[]byte("sales.us"), 10.5, date},
+				{"sales.eu", []byte("7"), date},
+			},
+		},
+	})
+
+	collector := &SQLCollector{
+		DB: db,
+		Queries: []SQLQuery{
+			{Query: "SELECT COUNT(*) FROM users", Key: "users"},
+			{
+				Query:       "SELECT country, SUM(amount), day FROM sales GROUP BY country, day",
+				ValueColumn: "amount",
+				DateColumn:  "day",
+				Unit:        "USD",
+			},
+		},
+	}
+	kpis, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	want := []KPI{
+		{Key: "users", Value: 42},
+		{Key: "sales.us", Value: 10.5, Date: "2020-01-02 03:04:05+00:00", Unit: "USD"},
+		{Key: "sales.eu", Value: 7, Date: "2020-01-02 03:04:05+00:00", Unit: "USD"},
+	}
+	if !reflect.DeepEqual(kpis, want) {
+		t.Errorf("got %+v, want %+v", kpis, want)
+	}
+
+	collector.Queries = []SQLQuery{{Query: "SELECT COUNT(*) FROM users", Key: "users", DateColumn: "day"}}
+	if _, err := collector.Collect(context.Background()); err == nil {
+		t.Error("Missing date column must fail")
+	}
+
+	collector.Queries = []SQLQuery{{Query: "SELECT COUNT(*) FROM users", Key: "users", ValueColumn: "total"}}
+	if _, err := collector.Collect(context.Background()); err == nil {
+		t.Error("Missing value column must fail")
+	}
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]map[string]fakeRows{}
+	// fakeTxs records the ends of transactions, "commit" or "rollback".
+	fakeTxs = map[string][]string{}
+	// fakeExecs records executed statements.
+	fakeExecs = map[string][]fakeExec{}
+)
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+// fakeFailCommit is a result key making commits of the database fail.
+const fakeFailCommit = "FAIL COMMIT"
+
+func init() {
+	sql.Register("databoxfake", fakeDriver{})
+}
+
+// openFakeDB returns a database answering queries with given results.
+func openFakeDB(t *testing.T, results map[string]fakeRows) *sql.DB {
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = results
+	fakeDBsMu.Unlock()
+	// The records are forgotten for the next run of the test.
+	t.Cleanup(func() {
+		fakeDBsMu.Lock()
+		delete(fakeTxs, t.Name())
+		delete(fakeExecs, t.Name())
+		fakeDBsMu.Unlock()
+	})
+
+	db, err := sql.Open("databoxfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	return &fakeConn{name: name, results: fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	name    string
+	results map[string]fakeRows
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	if _, ok := c.results[fakeFailCommit]; ok {
+		return errors.New("commit failed")
+	}
+	c.endTx("commit")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.endTx("rollback")
+	return nil
+}
+
+func (c *fakeConn) endTx(end string) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	fakeTxs[c.name] = append(fakeTxs[c.name], end)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	result, ok := c.results[query]
+	if !ok {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return &fakeRowsIter{fakeRows: result}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	exec := fakeExec{query: query}
+	for _, arg := range args {
+		exec.args = append(exec.args, arg.Value)
+	}
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	fakeExecs[c.name] = append(fakeExecs[c.name], exec)
+	return driver.RowsAffected(1), nil
+}
+
+type fakeRowsIter struct {
+	fakeRows
+	next int
+}
+
+func (r *fakeRowsIter) Columns() []string { return r.columns }
+
+func (r *fakeRowsIter) Close() error { return nil }
+
+func (r *fakeRowsIter) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
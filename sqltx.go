@@ -0,0 +1,38 @@
+package databox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithSQLTx runs f in a database transaction together with a Txn staging the
+// KPIs derived in it. If f succeeds, the database transaction is committed
+// and then the KPIs are pushed. If f or the database commit fails, both are
+// rolled back, also when f panics, so no metric of a rolled-back
+// transaction is reported.
+//
+// A failed push can't undo the database commit; the error is returned and
+// the KPIs are lost. Stage them in an outbox within the transaction instead,
+// see SQLOutbox, when they must survive.
+func WithSQLTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, pusher Pusher, f func(tx *sql.Tx, txn *Txn) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op after Commit.
+	defer tx.Rollback()
+	txn := NewTxn(pusher)
+	defer txn.Rollback()
+
+	if err := f(tx, txn); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if _, err := txn.Commit(ctx); err != nil {
+		return fmt.Errorf("transaction committed, pushing KPIs: %w", err)
+	}
+	return nil
+}
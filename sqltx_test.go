@@ -0,0 +1,58 @@
+package databox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWithSQLTx(t *testing.T) {
+	t.Parallel()
+
+	var posts int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		posts++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	work := func(err error) func(tx *sql.Tx, txn *Txn) error {
+		return func(tx *sql.Tx, txn *Txn) error {
+			txn.Stage(KPI{Key: "orders", Value: 1})
+			return err
+		}
+	}
+
+	db := openFakeDB(t, nil)
+	if err := WithSQLTx(context.Background(), db, nil, client, work(nil)); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	errWork := errors.New("work failed")
+	if err := WithSQLTx(context.Background(), db, nil, client, work(errWork)); !errors.Is(err, errWork) {
+		t.Error("Expected error of the work", err)
+	}
+	failing := openFakeDB(t, map[string]fakeRows{fakeFailCommit: {}})
+	if err := WithSQLTx(context.Background(), failing, nil, client, work(nil)); err == nil {
+		t.Error("Failed commit must be reported")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Panic must be propagated")
+			}
+		}()
+		_ = WithSQLTx(context.Background(), db, nil, client, func(tx *sql.Tx, txn *Txn) error {
+			txn.Stage(KPI{Key: "orders", Value: 1})
+			panic("boom")
+		})
+	}()
+
+	fakeDBsMu.Lock()
+	txs := fakeTxs[t.Name()]
+	fakeDBsMu.Unlock()
+	if posts != 1 || len(txs) != 3 || txs[0] != "commit" || txs[1] != "rollback" || txs[2] != "rollback" {
+		t.Error("Only KPIs of committed transaction must be pushed", posts, txs)
+	}
+}
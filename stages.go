@@ -0,0 +1,162 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TransformSet is a Transform whose named transforms can be added and
+// removed while the Client is pushing, e.g. to toggle feature-flagged
+// metrics without a restart. Add its Transform to Client.Transforms once.
+// TransformSet is safe for concurrent use.
+type TransformSet struct {
+	mu         sync.RWMutex
+	names      []string
+	transforms map[string]Transform
+}
+
+// Set adds the transform under name, after the others, or replaces the
+// transform of the name in place.
+func (s *TransformSet) Set(name string, transform Transform) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.transforms == nil {
+		s.transforms = make(map[string]Transform)
+	}
+	if _, ok := s.transforms[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.transforms[name] = transform
+}
+
+// Remove removes the transform of the name, if any.
+func (s *TransformSet) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.transforms[name]; !ok {
+		return
+	}
+	delete(s.transforms, name)
+	names := make([]string, 0, len(s.names)-1)
+	for _, n := range s.names {
+		if n != name {
+			names = append(names, n)
+		}
+	}
+	s.names = names
+}
+
+// Names returns the names of the transforms in the order they are applied.
+func (s *TransformSet) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.names...)
+}
+
+// Transform applies the transforms of the set in order. A push uses the
+// transforms set when it started.
+func (s *TransformSet) Transform(ctx context.Context, kpis []KPI) ([]KPI, error) {
+	s.mu.RLock()
+	transforms := make([]Transform, len(s.names))
+	for i, name := range s.names {
+		transforms[i] = s.transforms[name]
+	}
+	names := s.names
+	s.mu.RUnlock()
+
+	var err error
+	for i, transform := range transforms {
+		if kpis, err = transform(ctx, kpis); err != nil {
+			return nil, fmt.Errorf("transform %s: %w", names[i], err)
+		}
+	}
+	return kpis, nil
+}
+
+// CollectorSet is a Collector of named collectors which can be added and
+// removed while it is collected, e.g. by Client.CollectEvery. CollectorSet is
+// safe for concurrent use.
+type CollectorSet struct {
+	// OnError, if set, receives errors of individual collectors, and the
+	// KPIs of the others are still returned. Otherwise failures of the
+	// collectors fail the whole collection.
+	OnError func(name string, err error)
+
+	mu         sync.RWMutex
+	names      []string
+	collectors map[string]Collector
+}
+
+var _ Collector = (*CollectorSet)(nil)
+
+// Set adds the collector under name, or replaces the collector of the name.
+func (s *CollectorSet) Set(name string, collector Collector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.collectors == nil {
+		s.collectors = make(map[string]Collector)
+	}
+	if _, ok := s.collectors[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.collectors[name] = collector
+}
+
+// Remove removes the collector of the name, if any.
+func (s *CollectorSet) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.collectors[name]; !ok {
+		return
+	}
+	delete(s.collectors, name)
+	names := make([]string, 0, len(s.names)-1)
+	for _, n := range s.names {
+		if n != name {
+			names = append(names, n)
+		}
+	}
+	s.names = names
+}
+
+// Names returns the names of the collectors in the order they are collected.
+func (s *CollectorSet) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.names...)
+}
+
+// Collect implements Collector. It runs the collectors one by one,
+// recovering from their panics.
+func (s *CollectorSet) Collect(ctx context.Context) ([]KPI, error) {
+	s.mu.RLock()
+	collectors := make([]Collector, len(s.names))
+	for i, name := range s.names {
+		collectors[i] = s.collectors[name]
+	}
+	names := s.names
+	s.mu.RUnlock()
+
+	var kpis []KPI
+	var errs []error
+	for i, collector := range collectors {
+		collected, err := runCollector(ctx, collector)
+		if err != nil {
+			if s.OnError != nil {
+				if err := s.reportError(names[i], err); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			errs = append(errs, fmt.Errorf("collector %s: %w", names[i], err))
+			continue
+		}
+		kpis = append(kpis, collected...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return kpis, nil
+}
@@ -0,0 +1,103 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTransformSet(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var pushed []string
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	set := &TransformSet{}
+	client.Transforms = []Transform{set.Transform, TransformEach(func(kpi KPI) ([]KPI, error) {
+		mu.Lock()
+		pushed = append(pushed, kpi.Key)
+		mu.Unlock()
+		return []KPI{kpi}, nil
+	})}
+	prefix := func(p string) Transform {
+		return TransformEach(func(kpi KPI) ([]KPI, error) {
+			kpi.Key = p + kpi.Key
+			return []KPI{kpi}, nil
+		})
+	}
+
+	set.Set("a", prefix("a."))
+	set.Set("b", prefix("b."))
+	set.Set("a", prefix("x."))
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "k", Value: 1}}, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	set.Remove("b")
+	set.Remove("missing")
+	if _, err := client.InsertAll(context.Background(), []KPI{{Key: "k", Value: 1}}, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if !reflect.DeepEqual(pushed, []string{"b.x.k", "x.k"}) || !reflect.DeepEqual(set.Names(), []string{"a"}) {
+		t.Error("Unexpected keys", pushed, set.Names())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				set.Set("toggle", prefix("t."))
+				set.Remove("toggle")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, _ = client.InsertAll(context.Background(), []KPI{{Key: "k", Value: 1}}, false)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCollectorSet(t *testing.T) {
+	t.Parallel()
+
+	constant := func(key string) Collector {
+		return CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+			return []KPI{{Key: key, Value: 1}}, nil
+		})
+	}
+	set := &CollectorSet{}
+	set.Set("a", constant("a"))
+	set.Set("broken", CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+		return nil, errors.New("down")
+	}))
+	set.Set("b", constant("b"))
+
+	if _, err := set.Collect(context.Background()); err == nil || err.Error() != "collector broken: down" {
+		t.Error("Failure must fail the collection", err)
+	}
+
+	var failed []string
+	set.OnError = func(name string, err error) {
+		failed = append(failed, name)
+	}
+	kpis, err := set.Collect(context.Background())
+	if err != nil || len(kpis) != 2 || kpis[0].Key != "a" || kpis[1].Key != "b" || !reflect.DeepEqual(failed, []string{"broken"}) {
+		t.Error("Other collectors must be collected", kpis, err, failed)
+	}
+
+	set.Remove("a")
+	set.Remove("broken")
+	if kpis, err := set.Collect(context.Background()); err != nil || len(kpis) != 1 || kpis[0].Key != "b" {
+		t.Error("Removed collectors must not be collected", kpis, err)
+	}
+}
@@ -0,0 +1,190 @@
+package databox
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Stats counts deliveries of a Client. Set it to Client.Stats to enable the
+// counting. Stats is safe for concurrent use.
+type Stats struct {
+	// LatencyWindow is the sliding window of request latencies summarized
+	// in snapshots. Zero means 5 minutes.
+	LatencyWindow time.Duration
+
+	mu       sync.Mutex
+	pushes   uint64
+	failures uint64
+	retries  uint64
+	kpis     uint64
+	queued   int
+	keys     map[string]KeyStats
+	lastPush time.Time
+	// latencies is a ring of the latest request latencies.
+	latencies   []latencySample
+	latencyNext int
+}
+
+// StatsSnapshot is the state of Stats at one moment.
+type StatsSnapshot struct {
+	// Pushes is the number of successful push requests.
+	Pushes uint64
+	// Failures is the number of failed push requests.
+	Failures uint64
+	// Retries is the number of retried requests.
+	Retries uint64
+	// KPIs is the number of successfully pushed KPIs.
+	KPIs uint64
+	// Queued is the number of KPIs waiting in the Client's batches.
+	Queued int
+	// Keys is the number of successfully pushed values by metric key.
+	Keys map[string]uint64
+	// LastPush is the time of the last successful push.
+	LastPush time.Time
+	// Latency summarizes latencies of requests within the LatencyWindow.
+	Latency LatencySummary
+}
+
+// Snapshot returns a copy of the current counts.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := StatsSnapshot{
+		Pushes:   s.pushes,
+		Failures: s.failures,
+		Retries:  s.retries,
+		KPIs:     s.kpis,
+		Queued:   s.queued,
+		Keys:     make(map[string]uint64, len(s.keys)),
+		LastPush: s.lastPush,
+		Latency:  s.latencySummary(time.Now()),
+	}
+	for key, stats := range s.keys {
+		snapshot.Keys[key] = stats.Values
+	}
+	return snapshot
+}
+
+// KeyStats are the delivery statistics of a metric key.
+type KeyStats struct {
+	// Values is the number of successfully pushed values.
+	Values uint64
+	// LastPush is the time of the last successful push of the key.
+	LastPush time.Time
+}
+
+// ByKey returns the statistics of the metric keys pushed successfully, so
+// a service can tell which metrics it actually emits.
+func (s *Stats) ByKey() map[string]KeyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make(map[string]KeyStats, len(s.keys))
+	for key, stats := range s.keys {
+		keys[key] = stats
+	}
+	return keys
+}
+
+// Publish exports the snapshots as an expvar variable of the name, so they
+// appear on /debug/vars. Like expvar.Publish, it panics if the name is
+// already registered.
+func (s *Stats) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return s.Snapshot()
+	}))
+}
+
+// recordPush counts the result of pushing kpis. It's a no-op on nil Stats.
+func (s *Stats) recordPush(kpis []KPI, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures++
+		return
+	}
+	s.pushes++
+	s.kpis += uint64(len(kpis))
+	s.lastPush = time.Now()
+	if s.keys == nil {
+		s.keys = make(map[string]KeyStats)
+	}
+	pushed := func(key string) {
+		stats := s.keys[key]
+		stats.Values++
+		stats.LastPush = s.lastPush
+		s.keys[key] = stats
+	}
+	for _, kpi := range kpis {
+		if kpi.Key != "" {
+			pushed(kpi.Key)
+		}
+		for key := range kpi.Metrics {
+			pushed(key)
+		}
+	}
+}
+
+// recordRetry counts a retried request. It's a no-op on nil Stats.
+func (s *Stats) recordRetry() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.retries++
+	s.mu.Unlock()
+}
+
+// addQueued adjusts the number of queued KPIs. It's a no-op on nil Stats.
+func (s *Stats) addQueued(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.queued += n
+	s.mu.Unlock()
+}
+
+// Collector returns a Collector of the delivery statistics as KPIs with keys
+// prefixed by prefix: pushes, failures and retries since the previous
+// collection, success_rate of the pushes in percent, queued KPIs, and
+// latency_p50, latency_p90 and latency_p99 of the requests in milliseconds.
+// Push them with Client.CollectEvery, so the Databox board can show whether
+// its own data feed is healthy.
+func (s *Stats) Collector(prefix string) Collector {
+	var mu sync.Mutex
+	var previous StatsSnapshot
+	return CollectorFunc(func(ctx context.Context) ([]KPI, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		current := s.Snapshot()
+		pushes := current.Pushes - previous.Pushes
+		failures := current.Failures - previous.Failures
+		kpis := []KPI{
+			{Key: prefix + "pushes", Value: float32(pushes)},
+			{Key: prefix + "failures", Value: float32(failures)},
+			{Key: prefix + "retries", Value: float32(current.Retries - previous.Retries)},
+			{Key: prefix + "queued", Value: float32(current.Queued)},
+		}
+		if requests := pushes + failures; requests > 0 {
+			kpis = append(kpis, KPI{Key: prefix + "success_rate", Value: float32(pushes) / float32(requests) * 100, Unit: "%"})
+		}
+		if latency := current.Latency; latency.Count > 0 {
+			kpis = append(kpis,
+				KPI{Key: prefix + "latency_p50", Value: milliseconds(latency.P50), Unit: "ms"},
+				KPI{Key: prefix + "latency_p90", Value: milliseconds(latency.P90), Unit: "ms"},
+				KPI{Key: prefix + "latency_p99", Value: milliseconds(latency.P99), Unit: "ms"},
+			)
+		}
+		previous = current
+		return kpis, nil
+	})
+}
+
+func milliseconds(d time.Duration) float32 {
+	return float32(d) / float32(time.Millisecond)
+}
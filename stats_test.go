@@ -0,0 +1,124 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var statsPublished int32
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	statusCode := 500
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(statusCode, `{"id":"someRandomId"}`), nil
+	})
+	client.Backoff = ConstantBackoff{MaxRetries: 1}
+	client.Stats = &Stats{}
+	// expvar names can't be reused, so every run of the test publishes a new
+	// one.
+	name := fmt.Sprintf("databox_test_stats_%d", atomic.AddInt32(&statsPublished, 1))
+	client.Stats.Publish(name)
+
+	batch := client.NewBatch()
+	batch.Add(KPI{Key: "a", Value: 1}, KPI{Metrics: map[string]float32{"a": 2, "b": 3}})
+	if _, err := batch.Flush(context.Background()); err == nil {
+		t.Fatal("This should not be \"ok\"")
+	}
+	if snapshot := client.Stats.Snapshot(); snapshot.Failures != 1 || snapshot.Retries != 1 || snapshot.Queued != 2 {
+		t.Error("Unexpected stats", snapshot)
+	}
+
+	statusCode = 200
+	if _, err := batch.Flush(context.Background()); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	var snapshot StatsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Pushes != 1 || snapshot.KPIs != 2 || snapshot.Queued != 0 || snapshot.Keys["a"] != 2 || snapshot.Keys["b"] != 1 {
+		t.Error("Unexpected stats", snapshot)
+	}
+	if snapshot.Latency.Count != 3 {
+		t.Error("Latency of every attempt must be measured", snapshot.Latency)
+	}
+
+	byKey := client.Stats.ByKey()
+	if len(byKey) != 2 || byKey["a"].Values != 2 || byKey["b"].Values != 1 || !byKey["a"].LastPush.Equal(snapshot.LastPush) {
+		t.Error("Unexpected stats by key", byKey)
+	}
+}
+
+func TestStatsLatency(t *testing.T) {
+	t.Parallel()
+
+	stats := &Stats{LatencyWindow: time.Minute}
+	stats.recordLatency(time.Hour)
+	stats.latencies[0].at = time.Now().Add(-2 * time.Minute)
+	for i := 100; i > 0; i-- {
+		stats.recordLatency(time.Duration(i) * time.Millisecond)
+	}
+	latency := stats.Snapshot().Latency
+	if latency != (LatencySummary{Count: 100, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}) {
+		t.Error("Unexpected latency summary", latency)
+	}
+
+	kpis, _ := stats.Collector("databox_").Collect(context.Background())
+	if kpi := kpis[len(kpis)-3]; kpi.Key != "databox_latency_p50" || kpi.Value != 50 || kpi.Unit != "ms" {
+		t.Error("Unexpected latency KPI", kpi)
+	}
+
+	for i := 0; i < maxLatencySamples; i++ {
+		stats.recordLatency(time.Millisecond)
+	}
+	if latency := stats.Snapshot().Latency; len(stats.latencies) != maxLatencySamples || latency.Max != time.Millisecond {
+		t.Error("Oldest samples must be dropped", len(stats.latencies), latency)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	t.Parallel()
+
+	stats := &Stats{}
+	collector := stats.Collector("databox_")
+	stats.recordPush([]KPI{{Key: "a"}}, nil)
+	stats.recordPush([]KPI{{Key: "a"}}, nil)
+	stats.recordPush(nil, errors.New("fail"))
+	stats.recordPush([]KPI{{Key: "a"}}, nil)
+	stats.addQueued(5)
+
+	values := func() map[string]float32 {
+		kpis, err := collector.Collect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make(map[string]float32)
+		for _, kpi := range kpis {
+			values[kpi.Key] = kpi.Value
+		}
+		return values
+	}
+	got := values()
+	if got["databox_pushes"] != 3 || got["databox_failures"] != 1 || got["databox_success_rate"] != 75 || got["databox_queued"] != 5 {
+		t.Error("Unexpected self-metrics", got)
+	}
+
+	stats.recordPush([]KPI{{Key: "a"}}, nil)
+	got = values()
+	if got["databox_pushes"] != 1 || got["databox_failures"] != 0 || got["databox_success_rate"] != 100 {
+		t.Error("Counts must be since the previous collection", got)
+	}
+	if _, ok := values()["databox_success_rate"]; ok {
+		t.Error("Success rate without pushes must be omitted")
+	}
+}
@@ -0,0 +1,47 @@
+package databox
+
+import "context"
+
+// Pusher pushes KPIs to Databox. It's implemented by Client and by the views
+// returned from Tenants.Pusher.
+type Pusher interface {
+	PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error)
+	InsertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error)
+}
+
+var _ Pusher = (*Client)(nil)
+
+// Tenants produces tenant-scoped views of Client for multi-tenant backends.
+type Tenants struct {
+	Client *Client
+	// Attributes derives the attributes labelling KPIs of the tenant, e.g.
+	// {"tenant": tenant, "plan": "enterprise"}.
+	Attributes func(tenant string) map[string]interface{}
+}
+
+// Pusher returns a Pusher that labels every KPI with the attributes of
+// tenant. The tenant attributes override attributes of the same name set on
+// the KPI, so a KPI can't be reported under another tenant by mistake. If
+// Attributes panics, the pushes of the Pusher fail with *PanicError.
+func (t *Tenants) Pusher(tenant string) Pusher {
+	attributes, err := t.attributes(tenant)
+	return &tenantPusher{client: t.Client, attributes: attributes, err: err}
+}
+
+type tenantPusher struct {
+	client     *Client
+	attributes map[string]interface{}
+	// err is the panic of Tenants.Attributes.
+	err error
+}
+
+func (p *tenantPusher) PushCtx(ctx context.Context, kpi *KPI) (*ResponseStatus, error) {
+	return p.InsertAll(ctx, []KPI{*kpi}, false)
+}
+
+func (p *tenantPusher) InsertAll(ctx context.Context, kpis []KPI, forcePush bool) (*ResponseStatus, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.client.InsertAll(ctx, withAttributes(kpis, p.attributes, true), forcePush)
+}
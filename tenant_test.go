@@ -0,0 +1,46 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestTenantsPusher(t *testing.T) {
+	t.Parallel()
+
+	var pushed KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &pushed)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	tenants := &Tenants{
+		Client: client,
+		Attributes: func(tenant string) map[string]interface{} {
+			return map[string]interface{}{"tenant": tenant}
+		},
+	}
+	pusher := tenants.Pusher("acme")
+
+	ctx := WithAttributes(context.Background(), map[string]interface{}{"tenant": "globex", "job": "sync"})
+	kpis := []KPI{
+		{Key: "users", Value: 3},
+		{Key: "orders", Value: 5, Attributes: map[string]interface{}{"tenant": "initech"}},
+	}
+	if _, err := pusher.InsertAll(ctx, kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	for _, item := range pushed.Data {
+		if item["tenant"] != "acme" || item["job"] != "sync" {
+			t.Error("Unexpected attributes", item)
+		}
+	}
+	if kpis[1].Attributes["tenant"] != "initech" {
+		t.Error("KPI must not be modified")
+	}
+}
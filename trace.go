@@ -0,0 +1,100 @@
+package databox
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// TraceEvent records KPIs leaving a stage of the push pipeline. See
+// WithTrace.
+type TraceEvent struct {
+	Time time.Time
+	// Push identifies the traced push; events of one InsertAll call share
+	// it, including the pushes of its Router targets.
+	Push uint64
+	// Stage is the stage of the pipeline: "maintenance", "input",
+	// "transform N", "naming", "validation", "dedup", "downsample",
+	// "dry run", "review" and "push" of Client, and "router input",
+	// "router transform N", "route N", "default route" and "dropped" of
+	// Router.
+	Stage string
+	// Keys are the metric keys of the values leaving the stage, sorted.
+	Keys []string
+	// Detail is the response ID of a successful push.
+	Detail string
+	// Err is the error of the stage.
+	Err error
+}
+
+type traceKey struct{}
+
+// tracer is carried by the context of traced pushes.
+type tracer struct {
+	sink   func(TraceEvent)
+	dryRun bool
+	push   uint64
+}
+
+var tracePushes uint64
+
+// WithTrace returns a copy of ctx tracing the pushes made with it: sink is
+// called synchronously with every stage the KPIs pass, so a metric missing
+// on the board can be followed through transforms, deduplication and
+// routing to the final status. Panics of sink are recovered and ignored.
+func WithTrace(ctx context.Context, sink func(TraceEvent)) context.Context {
+	t := tracerFromContext(ctx)
+	return context.WithValue(ctx, traceKey{}, &tracer{sink: sink, dryRun: t != nil && t.dryRun})
+}
+
+// WithDryRun returns a copy of ctx with which Client pushes run the whole
+// pipeline but send nothing and return an empty ResponseStatus. Combined with
+// WithTrace it shows what would be pushed. Dry runs bypass Maintenance, and
+// no stage of the pipeline remembers their KPIs.
+func WithDryRun(ctx context.Context) context.Context {
+	t := &tracer{dryRun: true}
+	if parent := tracerFromContext(ctx); parent != nil {
+		t.sink, t.push = parent.sink, parent.push
+	}
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+func tracerFromContext(ctx context.Context) *tracer {
+	t, _ := ctx.Value(traceKey{}).(*tracer)
+	return t
+}
+
+// startTrace returns ctx identifying a new traced push, or ctx itself if it
+// isn't traced or the push is already identified, e.g. by Router.
+func startTrace(ctx context.Context) context.Context {
+	t := tracerFromContext(ctx)
+	if t == nil || t.push != 0 {
+		return ctx
+	}
+	push := *t
+	push.push = atomic.AddUint64(&tracePushes, 1)
+	return context.WithValue(ctx, traceKey{}, &push)
+}
+
+// trace records kpis leaving the stage, if ctx is traced.
+func trace(ctx context.Context, stage string, kpis []KPI, detail string, err error) {
+	t := tracerFromContext(ctx)
+	if t == nil || t.sink == nil {
+		return
+	}
+	var keys []string
+	for _, kpi := range kpis {
+		for key := range kpiValues(kpi) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	_ = t.emit(TraceEvent{Time: time.Now(), Push: t.push, Stage: stage, Keys: keys, Detail: detail, Err: err})
+}
+
+// isDryRun reports whether ctx was made by WithDryRun.
+func isDryRun(ctx context.Context) bool {
+	t := tracerFromContext(ctx)
+	return t != nil && t.dryRun
+}
@@ -0,0 +1,106 @@
+package databox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	client.Transforms = []Transform{TransformEach(func(kpi KPI) ([]KPI, error) {
+		if kpi.Key == "sales.skip" {
+			return nil, nil
+		}
+		return []KPI{kpi}, nil
+	})}
+	router := &Router{Routes: []Route{{Match: MatchKey(regexp.MustCompile(`^sales\.`)), Target: client}}}
+
+	var events []TraceEvent
+	ctx := WithTrace(context.Background(), func(event TraceEvent) {
+		events = append(events, event)
+	})
+	kpis := []KPI{{Key: "sales.eu", Value: 1}, {Key: "sales.skip", Value: 2}, {Key: "other", Value: 3}}
+	if _, err := router.InsertAll(ctx, kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+
+	var journey []string
+	for _, event := range events {
+		journey = append(journey, event.Stage+": "+strings.Join(event.Keys, ","))
+		if event.Push != events[0].Push || event.Push == 0 {
+			t.Error("Events of a push must share its ID", event)
+		}
+	}
+	want := []string{
+		"router input: other,sales.eu,sales.skip",
+		"route 0: sales.eu,sales.skip",
+		"input: sales.eu,sales.skip",
+		"transform 0: sales.eu",
+		"push: sales.eu",
+		"dropped: other",
+	}
+	if !reflect.DeepEqual(journey, want) {
+		t.Errorf("Unexpected journey\n got %q\nwant %q", journey, want)
+	}
+	if last := events[4]; last.Detail != "someRandomId" || last.Err != nil {
+		t.Error("Unexpected final status", last)
+	}
+
+	events = nil
+	if _, err := client.InsertAll(WithDryRun(ctx), kpis[:1], false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if requests != 1 || len(events) != 3 || events[2].Stage != "dry run" || events[0].Push == 0 {
+		t.Error("Dry run must be traced and not sent", requests, events)
+	}
+}
+
+func TestDryRunKeepsState(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+	aggregator := &RollupAggregator{Rollups: []Rollup{{Key: "sales", Target: "sales_weekly", Bucket: BucketWeek}}}
+	client.Transforms = []Transform{aggregator.Transform()}
+	client.AnomalyGuard = &AnomalyGuard{Rules: map[string]AnomalyRule{"sales": {MaxChange: 1}}}
+	client.UnitChecker = &UnitChecker{}
+
+	var events []TraceEvent
+	ctx := WithDryRun(WithTrace(context.Background(), func(event TraceEvent) {
+		events = append(events, event)
+	}))
+	kpis := []KPI{{Key: "sales", Value: 10, Date: "2020-05-11", Unit: "USD"}, {Key: "sales", Value: 5, Date: "2020-05-12", Unit: "USD"}}
+	if _, err := client.InsertAll(ctx, kpis, false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if last := events[len(events)-1]; last.Stage != "dry run" || !reflect.DeepEqual(last.Keys, []string{"sales", "sales", "sales_weekly"}) {
+		t.Error("Dry run must run the pipeline", last)
+	}
+	if len(bodies) != 0 || len(aggregator.buckets) != 0 || len(client.AnomalyGuard.last) != 0 || len(client.UnitChecker.units) != 0 {
+		t.Error("Dry run must not change the state", bodies, aggregator.buckets, client.AnomalyGuard.last, client.UnitChecker.units)
+	}
+
+	if _, err := client.InsertAll(context.Background(), kpis[1:], false); err != nil {
+		t.Fatal("Must be nil", err)
+	}
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"$sales_weekly":5`) {
+		t.Error("Rollup must not include dry run values", bodies)
+	}
+}
@@ -0,0 +1,270 @@
+//go:build !js && !tinygo
+
+package databox
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newHTTPClient returns the HTTP client with a transport tuned for pushing
+// to a single host.
+func (c *Client) newHTTPClient() *http.Client {
+	c.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = c.dialer.DialContext
+	// We use only one host: push.databox.com
+	transport.MaxIdleConnsPerHost = transport.MaxIdleConns
+	return &http.Client{Transport: transport, CheckRedirect: SafeRedirects}
+}
+
+// transport returns the transport created by NewClient, or nil if it was
+// replaced by a custom one or c is a copy made by With, which shares the
+// transport of its Client and has no dialer.
+func (c *Client) transport() *http.Transport {
+	if c.HTTPClient == nil || c.dialer == nil {
+		return nil
+	}
+	transport, _ := c.HTTPClient.Transport.(*http.Transport)
+	return transport
+}
+
+// WithMaxIdleConnsPerHost sets the number of idle connections kept to the
+// Databox API. High-throughput pushers benefit from more of them, cron jobs
+// need just one.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.MaxIdleConnsPerHost = n
+		}
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection is kept open. Zero
+// means no limit.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.IdleConnTimeout = d
+		}
+	}
+}
+
+// WithDialTimeout sets the timeout of establishing a TCP connection. The
+// default is 30 seconds. The timeout may be divided between the addresses
+// of the host, see net.Dialer.Timeout.
+func WithDialTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if c.dialer != nil {
+			c.dialer.Timeout = d
+		}
+	}
+}
+
+// WithFallbackDelay sets how long a dual-stack host is dialed over IPv6
+// before a parallel IPv4 attempt is started ("Happy Eyeballs"), 300ms by
+// default. Sites with flaky IPv6 can shorten it; negative disables the
+// fallback, so the addresses are dialed one by one. See WithNetwork to use
+// IPv4 only.
+func WithFallbackDelay(d time.Duration) Option {
+	return func(c *Client) {
+		if c.dialer != nil {
+			c.dialer.FallbackDelay = d
+		}
+	}
+}
+
+// WithTLSHandshakeTimeout sets the timeout of the TLS handshake. The default
+// is 10 seconds.
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.TLSHandshakeTimeout = d
+		}
+	}
+}
+
+// WithHTTP1 disables HTTP/2, so only HTTP/1.1 is used.
+func WithHTTP1() Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.ForceAttemptHTTP2 = false
+			transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+			if transport.TLSClientConfig != nil {
+				// Don't offer h2 in ALPN, the transport can't speak it anymore.
+				config := transport.TLSClientConfig.Clone()
+				config.NextProtos = nil
+				transport.TLSClientConfig = config
+			}
+		}
+	}
+}
+
+// WithHTTP2 makes the client attempt HTTP/2 even with customized dialer and
+// TLS configuration. HTTP/1.1 is used only if the server doesn't support
+// HTTP/2.
+func WithHTTP2() Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.ForceAttemptHTTP2 = true
+			transport.TLSNextProto = nil
+		}
+	}
+}
+
+// WithHTTP2Ping enables health checks of idle HTTP/2 connections: a ping is
+// sent after interval without any frame received, and the connection is
+// closed if the ping isn't answered within timeout. Dead connections, e.g.
+// dropped by a NAT, are detected before the next push instead of stalling
+// it. Zero timeout means 15 seconds. It implies WithHTTP2.
+func WithHTTP2Ping(interval, timeout time.Duration) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			WithHTTP2()(c)
+			config := &http.HTTP2Config{}
+			if transport.HTTP2 != nil {
+				*config = *transport.HTTP2
+			}
+			config.SendPingTimeout = interval
+			config.PingTimeout = timeout
+			transport.HTTP2 = config
+		}
+	}
+}
+
+// WithHTTP2PriorKnowledge makes the client speak HTTP/2 over unencrypted
+// connections without the HTTP/1.1 upgrade, e.g. to a local forwarder at
+// an http:// PushHost. It's meant for http:// hosts only: HTTP/1.1 is
+// disabled altogether, so HTTPS connections offer only HTTP/2 and fail
+// against servers without it.
+func WithHTTP2PriorKnowledge() Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			WithHTTP2()(c)
+			protocols := &http.Protocols{}
+			protocols.SetHTTP2(true)
+			protocols.SetUnencryptedHTTP2(true)
+			transport.Protocols = protocols
+		}
+	}
+}
+
+// WithTCPKeepAlive sets the interval of TCP keep-alive probes of the
+// connections, 30 seconds by default, so idle connections survive NATs and
+// firewalls dropping silent flows. Negative disables the probes.
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(c *Client) {
+		if c.dialer != nil {
+			c.dialer.KeepAlive = d
+		}
+	}
+}
+
+// WithResolver sets the resolver used by the Client's dialer.
+func WithResolver(resolver *net.Resolver) Option {
+	return func(c *Client) {
+		if c.dialer != nil {
+			c.dialer.Resolver = resolver
+		}
+	}
+}
+
+// WithDNSCache makes the Client resolve hosts through cache.
+func WithDNSCache(cache *DNSCache) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil && c.dialer != nil {
+			transport.DialContext = cache.dialContext(c.dialer)
+		}
+	}
+}
+
+// WithDialContext replaces the dial function of the Client's transport, e.g.
+// with one of a custom dialer. It overrides WithDialTimeout, WithResolver and
+// WithDNSCache.
+func WithDialContext(dial func(ctx context.Context, network, address string) (net.Conn, error)) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			transport.DialContext = dial
+		}
+	}
+}
+
+// WithNetwork forces the address family of the connections, "tcp4" or
+// "tcp6", e.g. when the IPv6 route to the API is broken.
+func WithNetwork(network string) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			dial := transport.DialContext
+			transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+				return dial(ctx, network, address)
+			}
+		}
+	}
+}
+
+// WithDialAddress makes the Client connect to address instead of resolving
+// the PushHost. TLS server name and Host header stay the PushHost's. If the
+// address has no port, the PushHost's port is used.
+func WithDialAddress(address string) Option {
+	return func(c *Client) {
+		if transport := c.transport(); transport != nil {
+			dial := transport.DialContext
+			transport.DialContext = func(ctx context.Context, network, hostport string) (net.Conn, error) {
+				if _, _, err := net.SplitHostPort(address); err == nil {
+					return dial(ctx, network, address)
+				}
+				_, port, err := net.SplitHostPort(hostport)
+				if err != nil {
+					return nil, err
+				}
+				return dial(ctx, network, net.JoinHostPort(address, port))
+			}
+		}
+	}
+}
+
+// PinSPKI restricts connections to servers whose certificate chain contains
+// a public key with one of the pins, see SPKIPin. The check is done after
+// the regular certificate verification and only considers the verified
+// chains, so certificates the server sends in excess never match. Connections
+// failing it are closed with *PinError. The HTTPClient must use
+// *http.Transport.
+func (c *Client) PinSPKI(pins ...string) error {
+	if len(pins) == 0 {
+		return errors.New("no pins given")
+	}
+	transport, ok := c.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("transport %T doesn't support pinning", c.HTTPClient.Transport)
+	}
+
+	allowed := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		allowed[pin] = true
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		pinErr := &PinError{Host: state.ServerName}
+		for _, chain := range state.VerifiedChains {
+			for _, certificate := range chain {
+				pin := SPKIPin(certificate)
+				if allowed[pin] {
+					return nil
+				}
+				pinErr.Pins = append(pinErr.Pins, pin)
+			}
+		}
+		return pinErr
+	}
+	return nil
+}
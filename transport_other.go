@@ -0,0 +1,21 @@
+//go:build js || tinygo
+
+package databox
+
+import (
+	"errors"
+	"net/http"
+)
+
+// newHTTPClient returns the HTTP client with the platform's default
+// transport, e.g. the Fetch API under GOOS=js, which can't be tuned.
+func (c *Client) newHTTPClient() *http.Client {
+	return &http.Client{CheckRedirect: SafeRedirects}
+}
+
+// PinSPKI is not supported on this platform, the TLS connections are not
+// under the transport's control. It always fails, so the pinning fails
+// closed.
+func (c *Client) PinSPKI(pins ...string) error {
+	return errors.New("certificate pinning is not supported on this platform")
+}
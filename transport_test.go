@@ -0,0 +1,140 @@
+//go:build !js && !tinygo
+
+package databox
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransportOptions(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken(),
+		WithMaxIdleConnsPerHost(4),
+		WithIdleConnTimeout(time.Minute),
+		WithDialTimeout(2*time.Second),
+		WithFallbackDelay(50*time.Millisecond),
+		WithTLSHandshakeTimeout(3*time.Second),
+	)
+	transport := client.HTTPClient.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 4 || transport.IdleConnTimeout != time.Minute || transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Error("Options must be applied to transport")
+	}
+	if client.dialer.Timeout != 2*time.Second || client.dialer.FallbackDelay != 50*time.Millisecond {
+		t.Error("Dialer options must be set", client.dialer)
+	}
+}
+
+func TestHTTPVersionOptions(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		_, _ = w.Write([]byte(`{"id":"someRandomId"}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for opt, want := range map[string]Option{"HTTP/1.1": WithHTTP1(), "HTTP/2.0": WithHTTP2()} {
+		client := NewClient(getToken(), want)
+		client.PushHost = server.URL
+		client.transport().TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		response, err := client.Do(t.Context(), "GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.Proto != opt {
+			t.Errorf("got %s, want %s", response.Proto, opt)
+		}
+	}
+}
+
+func TestHTTP2Options(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"someRandomId"}`))
+	})
+	h2c := httptest.NewUnstartedServer(handler)
+	h2c.Config.Protocols = &http.Protocols{}
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	client := NewClient(getToken(), WithHTTP2PriorKnowledge(), WithTCPKeepAlive(time.Minute))
+	client.PushHost = h2c.URL
+	response, err := client.Do(t.Context(), "GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.ProtoMajor != 2 || client.dialer.KeepAlive != time.Minute {
+		t.Error("HTTP/2 must be used without TLS, got", response.Proto)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client = NewClient(getToken(), WithHTTP1(), WithHTTP2Ping(time.Minute, 5*time.Second))
+	client.PushHost = server.URL
+	client.transport().TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	if config := client.transport().HTTP2; config == nil || config.SendPingTimeout != time.Minute || config.PingTimeout != 5*time.Second {
+		t.Error("Ping must be configured", config)
+	}
+	response, err = client.Do(t.Context(), "GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.ProtoMajor != 2 {
+		t.Error("HTTP/2 must be used, got", response.Proto)
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "databox.invalid:"+r.URL.Query().Get("port") {
+			t.Error("Host must be kept", r.Host)
+		}
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	path := "/?port=" + port
+
+	client := NewClient(getToken(), WithDialAddress("127.0.0.1"), WithNetwork("tcp4"))
+	client.PushHost = "http://databox.invalid:" + port
+	response, err := client.Do(t.Context(), "GET", path, nil)
+	if err != nil {
+		t.Fatal("Overridden address must be dialed", err)
+	}
+	response.Body.Close()
+
+	client = NewClient(getToken(), WithDialAddress(server.Listener.Addr().String()), WithNetwork("tcp6"))
+	client.PushHost = "http://databox.invalid:" + port
+	if _, err := client.Do(t.Context(), "GET", path, nil); err == nil {
+		t.Error("IPv4 address must not be dialed over tcp6")
+	}
+}
+
+func TestClientWithTransport(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(getToken(), WithIdleConnTimeout(time.Minute), WithDialTimeout(2*time.Second))
+	derived := client.With(WithIdleConnTimeout(time.Second), WithDialTimeout(time.Second), WithDialContext(nil))
+	if derived.HTTPClient.Transport != client.HTTPClient.Transport {
+		t.Error("Transport must be shared")
+	}
+	if transport := client.transport(); transport.IdleConnTimeout != time.Minute || transport.DialContext == nil || client.dialer.Timeout != 2*time.Second {
+		t.Error("Transport options of the copy must be ignored")
+	}
+}
@@ -0,0 +1,69 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTxnDone is returned by Txn.Commit when the Txn was already committed or
+// rolled back.
+var ErrTxnDone = errors.New("transaction has already been committed or rolled back")
+
+// Txn stages KPIs of a unit of work, which are pushed only when the work
+// succeeds, so rolled-back operations leave no metrics behind. Txn is safe for
+// concurrent use.
+type Txn struct {
+	pusher Pusher
+
+	mu   sync.Mutex
+	kpis []KPI
+	done bool
+}
+
+// NewTxn returns an empty Txn pushing its KPIs with pusher, e.g. Client or a
+// tenant Pusher.
+func NewTxn(pusher Pusher) *Txn {
+	return &Txn{pusher: pusher}
+}
+
+// Stage adds kpis to the transaction. KPIs staged after Commit or Rollback
+// are discarded.
+func (t *Txn) Stage(kpis ...KPI) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.done {
+		t.kpis = append(t.kpis, kpis...)
+	}
+}
+
+// Commit pushes the staged KPIs in one request and ends the transaction.
+// Nothing is sent if no KPI was staged. When the push fails, the
+// transaction stays open, so the Commit can be repeated, or rolled back.
+func (t *Txn) Commit(ctx context.Context) (*ResponseStatus, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return nil, ErrTxnDone
+	}
+	if len(t.kpis) == 0 {
+		t.done = true
+		return nil, nil
+	}
+	responseStatus, err := t.pusher.InsertAll(ctx, t.kpis, false)
+	if err != nil {
+		return nil, err
+	}
+	t.kpis = nil
+	t.done = true
+	return responseStatus, nil
+}
+
+// Rollback discards the staged KPIs and ends the transaction. Rollback after
+// Commit does nothing, so it can be deferred.
+func (t *Txn) Rollback() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.kpis = nil
+	t.done = true
+}
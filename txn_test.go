@@ -0,0 +1,50 @@
+package databox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestTxn(t *testing.T) {
+	t.Parallel()
+
+	var requests []KPIWrap
+	statusCode := 500
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var wrap KPIWrap
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &wrap)
+		requests = append(requests, wrap)
+		return jsonResponse(statusCode, `{"id":"someRandomId","type":"error","message":"fail"}`), nil
+	})
+
+	rolledBack := NewTxn(client)
+	rolledBack.Stage(KPI{Key: "orders", Value: 1})
+	rolledBack.Rollback()
+	if _, err := rolledBack.Commit(context.Background()); !errors.Is(err, ErrTxnDone) || len(requests) != 0 {
+		t.Error("Rolled back KPIs must not be pushed", err)
+	}
+
+	txn := NewTxn(client)
+	defer txn.Rollback()
+	txn.Stage(KPI{Key: "orders", Value: 1})
+	txn.Stage(KPI{Key: "revenue", Value: 20})
+	if _, err := txn.Commit(context.Background()); err == nil {
+		t.Error("This should not be \"ok\"")
+	}
+	statusCode = 200
+	if _, err := txn.Commit(context.Background()); err != nil {
+		t.Fatal("Failed commit must be repeatable", err)
+	}
+	if len(requests) != 2 || len(requests[1].Data) != 2 {
+		t.Error("Staged KPIs must be pushed in one request", requests)
+	}
+	if _, err := txn.Commit(context.Background()); !errors.Is(err, ErrTxnDone) {
+		t.Error("Expected ErrTxnDone", err)
+	}
+}
@@ -0,0 +1,51 @@
+package databox
+
+import "sync"
+
+// UnitChecker remembers the unit used for every metric key and warns through
+// Client.Logger when a push uses a different unit for the same key. Mixed
+// units of a metric make confusing dashboards. The zero value is ready to
+// use, and UnitChecker is safe for concurrent use.
+type UnitChecker struct {
+	mu    sync.Mutex
+	units map[string]string
+}
+
+// check compares the units of kpis with the previous ones. The returned
+// commit function remembers the new units; it's meant to be called after a
+// successful push.
+func (u *UnitChecker) check(kpis []KPI, logger Logger) func() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	units := make(map[string]string)
+	for _, kpi := range kpis {
+		if kpi.Key != "" {
+			u.checkMetric(units, kpi.Key, kpi.Unit, logger)
+		}
+		for key := range kpi.Metrics {
+			u.checkMetric(units, key, kpi.Unit, logger)
+		}
+	}
+	return func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if u.units == nil {
+			u.units = make(map[string]string)
+		}
+		for key, unit := range units {
+			u.units[key] = unit
+		}
+	}
+}
+
+func (u *UnitChecker) checkMetric(units map[string]string, key, unit string, logger Logger) {
+	previous, ok := units[key]
+	if !ok {
+		previous, ok = u.units[key]
+	}
+	if ok && previous != unit && logger != nil {
+		logger.Printf("databox: metric %q pushed with unit %q, previously %q", key, unit, previous)
+	}
+	units[key] = unit
+}
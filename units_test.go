@@ -0,0 +1,34 @@
+package databox
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestUnitChecker(t *testing.T) {
+	t.Parallel()
+
+	var logger loggerMock
+	client := NewClient(getToken())
+	client.Logger = &logger
+	client.UnitChecker = &UnitChecker{}
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	for _, kpi := range []KPI{
+		{Key: "revenue", Value: 1, Unit: "USD"},
+		{Key: "revenue", Value: 2, Unit: "USD"},
+		{Metrics: map[string]float32{"revenue": 3, "costs": 1}, Unit: "EUR"},
+		{Key: "costs", Value: 2, Unit: "EUR"},
+	} {
+		if _, err := client.InsertAll(context.Background(), []KPI{kpi}, false); err != nil {
+			t.Fatal("Must be nil", err)
+		}
+	}
+	want := `databox: metric "revenue" pushed with unit "EUR", previously "USD"`
+	if len(logger) != 1 || logger[0] != want {
+		t.Error("Unexpected warnings", logger)
+	}
+}
@@ -0,0 +1,48 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ErrVerificationFailed is returned when Client.VerifyPushes is enabled and
+// the push recorded by Databox doesn't match the sent KPIs. The
+// ResponseStatus of the push is returned along with the error.
+var ErrVerificationFailed = errors.New("push verification failed")
+
+// verifyPush looks up the push with given ID and checks that it carries the
+// same number of items with the same keys as kpis.
+func (c *Client) verifyPush(ctx context.Context, id string, kpis []KPI) error {
+	lastPush, err := c.FindPush(ctx, id)
+	if errors.Is(err, ErrPushNotFound) {
+		return fmt.Errorf("%w: push %q is not in the history", ErrVerificationFailed, id)
+	}
+	if err != nil {
+		return fmt.Errorf("verifying push %q: %w", id, err)
+	}
+
+	data := lastPush.Request.Body.Data
+	if len(data) != len(kpis) {
+		return fmt.Errorf("%w: push %q has %d items, %d sent", ErrVerificationFailed, id, len(data), len(kpis))
+	}
+	for i := range kpis {
+		sent, recorded := payloadKeys(kpis[i].ToJSONData()), payloadKeys(data[i])
+		if !reflect.DeepEqual(sent, recorded) {
+			return fmt.Errorf("%w: push %q item %d has keys %v, %v sent", ErrVerificationFailed, id, i, recorded, sent)
+		}
+	}
+	return nil
+}
+
+// payloadKeys returns sorted keys of serialized KPI.
+func payloadKeys(payload map[string]interface{}) []string {
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
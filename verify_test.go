@@ -0,0 +1,42 @@
+package databox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestVerifyPushes(t *testing.T) {
+	t.Parallel()
+
+	history := `[{"request":{"body":{"data":[{"$temp.ny":52,"date":"2015-01-01 09:00:00"}]}},"response":{"body":{"id":"abc"}}}]`
+	newClient := func() *Client {
+		client := NewClient(getToken())
+		client.VerifyPushes = true
+		client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Method == http.MethodPost {
+				return jsonResponse(200, `{"id":"abc"}`), nil
+			}
+			return jsonResponse(200, history), nil
+		})
+		return client
+	}
+
+	if _, err := newClient().Push(&KPI{Key: "temp.ny", Value: 52, Date: "2015-01-01 09:00:00"}); err != nil {
+		t.Error("Must be nil", err)
+	}
+
+	status, err := newClient().Push(&KPI{Key: "temp.la", Value: 52, Date: "2015-01-01 09:00:00"})
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Error("Expected ErrVerificationFailed", err)
+	}
+	if status == nil || status.ID != "abc" {
+		t.Error("Response status must be returned", status)
+	}
+
+	_, err = newClient().InsertAll(context.Background(), []KPI{{Key: "temp.ny", Value: 52}, {Key: "temp.la", Value: 52}}, false)
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Error("Expected ErrVerificationFailed", err)
+	}
+}
@@ -0,0 +1,135 @@
+package databox
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookMapping converts a field of an inbound webhook payload to a KPI.
+// Paths use the syntax of HTTPMapping.Path.
+type WebhookMapping struct {
+	// Key is the metric key of the KPI.
+	Key string
+	// Path selects the numeric value in the payload.
+	Path string
+	// DatePath optionally selects the date of the metric in the payload.
+	DatePath string
+	// Attributes maps attribute names to paths of their values.
+	Attributes map[string]string
+	// Unit is set to the KPI.
+	Unit string
+}
+
+// DefaultMaxBodyBytes is the size limit of request bodies accepted by the
+// handlers if their MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 1 << 20
+
+// WebhookHandler is an http.Handler relaying inbound JSON webhooks to Databox.
+// Each POST request is converted to KPIs according to Mappings, and the KPIs
+// are pushed in a single request.
+type WebhookHandler struct {
+	Client   *Client
+	Mappings []WebhookMapping
+	// Token, if set, must be sent by the caller in the X-Webhook-Token header.
+	Token string
+	// QueryToken also accepts the Token in the token query parameter, for
+	// senders that can't set headers. URLs tend to end up in logs.
+	QueryToken bool
+	// MaxBodyBytes caps the size of the payload. Zero means
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// ServeHTTP implements http.Handler. It responds 202 Accepted when the KPIs
+// are pushed, 400 Bad Request when the payload doesn't match the mappings,
+// 413 Request Entity Too Large when it exceeds MaxBodyBytes and 502 Bad
+// Gateway when the push fails.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Token != "" && !h.validToken(r) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	var payload interface{}
+	decoder := json.NewDecoder(maxBytesReader(w, r.Body, h.MaxBodyBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	kpis, err := h.convert(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.Client.InsertAll(r.Context(), kpis, false); err != nil {
+		http.Error(w, "pushing KPIs failed", http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validToken compares the token of r with h.Token in constant time.
+func (h *WebhookHandler) validToken(r *http.Request) bool {
+	token := r.Header.Get("X-Webhook-Token")
+	if token == "" && h.QueryToken {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) == 1
+}
+
+// maxBytesReader limits body to max bytes, or DefaultMaxBodyBytes if max is
+// zero.
+func maxBytesReader(w http.ResponseWriter, body io.ReadCloser, max int64) io.ReadCloser {
+	if max <= 0 {
+		max = DefaultMaxBodyBytes
+	}
+	return http.MaxBytesReader(w, body, max)
+}
+
+// convert applies the mappings to the webhook payload.
+func (h *WebhookHandler) convert(payload interface{}) ([]KPI, error) {
+	kpis := make([]KPI, 0, len(h.Mappings))
+	for _, mapping := range h.Mappings {
+		value, err := jsonPathValue(payload, mapping.Path, NumberFormat{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", mapping.Key, err)
+		}
+		kpi := KPI{Key: mapping.Key, Value: value, Unit: mapping.Unit}
+
+		if mapping.DatePath != "" {
+			date, err := jsonPath(payload, mapping.DatePath)
+			if err != nil {
+				return nil, fmt.Errorf("%s date: %w", mapping.Key, err)
+			}
+			kpi.Date = fmt.Sprint(date)
+		}
+		for name, path := range mapping.Attributes {
+			value, err := jsonPath(payload, path)
+			if err != nil {
+				return nil, fmt.Errorf("%s attribute %s: %w", mapping.Key, name, err)
+			}
+			if kpi.Attributes == nil {
+				kpi.Attributes = make(map[string]interface{})
+			}
+			kpi.Attributes[name] = value
+		}
+		kpis = append(kpis, kpi)
+	}
+	return kpis, nil
+}
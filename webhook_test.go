@@ -0,0 +1,84 @@
+package databox
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	t.Parallel()
+
+	var pushed KPIWrap
+	client := NewClient(getToken())
+	client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &pushed)
+		return jsonResponse(200, `{"id":"someRandomId"}`), nil
+	})
+
+	handler := &WebhookHandler{
+		Client: client,
+		Token:  "secret",
+		Mappings: []WebhookMapping{{
+			Key:        "orders.total",
+			Path:       "$.order.total",
+			DatePath:   "$.order.created_at",
+			Attributes: map[string]string{"country": "$.order.customer.country"},
+			Unit:       "EUR",
+		}},
+	}
+
+	serve := func(method, target, body string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if !strings.Contains(target, "token=") {
+			request.Header.Set("X-Webhook-Token", "secret")
+		}
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	payload := `{"order":{"total":"19.99","created_at":"2020-01-02 03:04:05","customer":{"country":"SI"}}}`
+	if code := serve("POST", "/", payload); code != http.StatusAccepted {
+		t.Fatal("Expected 202, got", code)
+	}
+	want := map[string]interface{}{
+		"$orders.total": 19.99,
+		"date":          "2020-01-02 03:04:05",
+		"country":       "SI",
+		"unit":          "EUR",
+	}
+	if len(pushed.Data) != 1 || len(pushed.Data[0]) != len(want) {
+		t.Fatal("Unexpected push", pushed)
+	}
+	for key, value := range want {
+		if pushed.Data[0][key] != value {
+			t.Errorf("%s: got %v, want %v", key, pushed.Data[0][key], value)
+		}
+	}
+
+	if code := serve("POST", "/?token=wrong", payload); code != http.StatusUnauthorized {
+		t.Error("Expected 401, got", code)
+	}
+	if code := serve("POST", "/?token=secret", payload); code != http.StatusUnauthorized {
+		t.Error("Query token must be opt-in, got", code)
+	}
+	handler.QueryToken = true
+	if code := serve("POST", "/?token=secret", payload); code != http.StatusAccepted {
+		t.Error("Expected 202, got", code)
+	}
+	if code := serve("GET", "/", ""); code != http.StatusMethodNotAllowed {
+		t.Error("Expected 405, got", code)
+	}
+	if code := serve("POST", "/", `{"order":{}}`); code != http.StatusBadRequest {
+		t.Error("Expected 400, got", code)
+	}
+	handler.MaxBodyBytes = 10
+	if code := serve("POST", "/", payload); code != http.StatusRequestEntityTooLarge {
+		t.Error("Expected 413, got", code)
+	}
+}
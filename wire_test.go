@@ -0,0 +1,84 @@
+package databox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wireFixture locks the payload pushed for the KPIs. Fixtures are never
+// regenerated: a change of the expected payload is a change of the wire
+// format and must be reviewed as such.
+type wireFixture struct {
+	Description string
+	KPIs        []struct {
+		Key        string
+		Value      float32
+		Metrics    map[string]float32
+		Date       string
+		Unit       string
+		Attributes map[string]interface{}
+	}
+	ForcePush bool            `json:"force_push"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func TestWireCompatibility(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "wire", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatal("No fixtures", err)
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fixture wireFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatal("Invalid fixture", err)
+			}
+			var want bytes.Buffer
+			if err := json.Compact(&want, fixture.Payload); err != nil {
+				t.Fatal("Invalid payload", err)
+			}
+			kpis := make([]KPI, len(fixture.KPIs))
+			for i, kpi := range fixture.KPIs {
+				kpis[i] = KPI(kpi)
+			}
+
+			for _, serializer := range []Serializer{nil, AppendSerializer{}} {
+				var got []byte
+				client := NewClient(getToken())
+				client.Serializer = serializer
+				client.HTTPClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					got, _ = io.ReadAll(r.Body)
+					return jsonResponse(200, `{"id":"someRandomId"}`), nil
+				})
+				if _, err := client.InsertAll(context.Background(), kpis, fixture.ForcePush); err != nil {
+					t.Fatal("Must be nil", err)
+				}
+				if serializer == nil {
+					// The default JSONSerializer sorts object keys, so
+					// the payload is compared byte by byte.
+					if !bytes.Equal(got, want.Bytes()) {
+						t.Errorf("Payload changed\n got %s\nwant %s", got, want.Bytes())
+					}
+					continue
+				}
+				assertJSONEqual(t, got, want.Bytes())
+			}
+		})
+	}
+}